@@ -2,23 +2,43 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"os"
 
 	_ "code.cestus.io/tools/fabricator"
+	"code.cestus.io/tools/fabricator/internal/pkg/util"
 	"code.cestus.io/tools/fabricator/pkg/cmd"
 	"code.cestus.io/tools/fabricator/pkg/fabricator"
 	"code.cestus.io/tools/fabricator/pkg/helpers"
 )
 
 func main() {
-	ctx := context.Background()
+	// Arguments following the name of a plugin are the plugin's own.
+	ctx := fabricator.ContextWithArgs(context.Background(), cmd.RootArgs(os.Args[1:]))
 
 	io := fabricator.NewStdIOStreams()
+	logger, err := helpers.NewLoggerFromFlags(ctx, io.ErrOut, helpers.DefaultFlagParser)
+	if err != nil {
+		fmt.Fprintf(io.ErrOut, "error: %v\n", err)
+		os.Exit(fabricator.ExitUsage)
+	}
+	io.Logger = logger
+	slog.SetDefault(logger)
+	ctx = fabricator.ContextWithLogger(ctx, logger)
+
 	ctx, cancel := helpers.WithCancelOnSignal(ctx, io, fabricator.TerminationSignals...)
 	defer cancel()
 	rootCmd := cmd.NewDefaultFabricatorCommand(ctx, io, helpers.DefaultFlagParser)
 
 	if err := rootCmd.ExecuteContext(ctx); err != nil {
-		os.Exit(1)
+		util.PrintErr(io.ErrOut, err)
+		code := fabricator.ExitCode(err)
+		var interrupted *fabricator.InterruptedError
+		if errors.As(context.Cause(ctx), &interrupted) {
+			code = interrupted.ExitCode()
+		}
+		os.Exit(code)
 	}
 }
@@ -1,89 +1,68 @@
 package util
 
 import (
+	"errors"
 	"fmt"
 	"io"
-	"os"
 	"strings"
 
+	"code.cestus.io/tools/fabricator/pkg/fabricator"
 	"github.com/spf13/cobra"
 )
 
 const (
-	DefaultErrorExitCode = 1
+	DefaultErrorExitCode = fabricator.ExitError
 )
 
-var fatalErrHandler = fatal
-
-// BehaviorOnFatal allows you to override the default behavior when a fatal
-// error occurs, which is to call os.Exit(code). You can pass 'panic' as a function
-// here if you prefer the panic() over os.Exit(1).
-func BehaviorOnFatal(f func(string, int)) {
-	fatalErrHandler = f
-}
-
-// DefaultBehaviorOnFatal allows you to undo any previous override.  Useful in
-// tests.
-func DefaultBehaviorOnFatal() {
-	fatalErrHandler = fatal
-}
-
-// fatal prints the message (if provided) and then exits. If V(6) or greater,
-// klog.Fatal is invoked for extended information.
-func fatal(msg string, code int) {
-	if len(msg) > 0 {
-		// add newline if needed
-		if !strings.HasSuffix(msg, "\n") {
-			msg += "\n"
-		}
-		fmt.Fprint(os.Stderr, msg)
-	}
-	os.Exit(code)
-}
-
-// ErrExit may be passed to CheckError to instruct it to output nothing but exit with
-// status code 1.
+// ErrExit may be returned by commands to exit with DefaultErrorExitCode
+// without printing an error.
 var ErrExit = fmt.Errorf("exit")
 
-// CheckErr prints a user friendly error to STDERR and exits with a non-zero
-// exit code. Unrecognized errors will be printed with an "error: " prefix.
+// PrintErr prints a user friendly error to w. Unrecognized errors will be
+// printed with an "error: " prefix; ErrExit is not printed.
 //
 // This method is generic to the command in use and may be used by non-fabricator
 // commands.
-func CheckErr(err error) {
-	checkErr(err, fatalErrHandler)
-}
-
-// checkErr formats a given error as a string and calls the passed handleErr
-// func with that string and an kubectl exit code.
-func checkErr(err error, handleErr func(string, int)) {
-
-	if err == nil {
+func PrintErr(w io.Writer, err error) {
+	if err == nil || errors.Is(err, ErrExit) {
 		return
 	}
-	fatalErrHandler(err.Error(), DefaultErrorExitCode)
+	msg := err.Error()
+	if !strings.HasPrefix(msg, "error: ") {
+		msg = "error: " + msg
+	}
+	// add newline if needed
+	if !strings.HasSuffix(msg, "\n") {
+		msg += "\n"
+	}
+	fmt.Fprint(w, msg)
 }
 
 // DefaultSubCommandRun prints a command's help string to the specified output if no
-// arguments (sub-commands) are provided, or a usage error otherwise.
-func DefaultSubCommandRun(out io.Writer) func(c *cobra.Command, args []string) {
-	return func(c *cobra.Command, args []string) {
+// arguments (sub-commands) are provided, or returns a usage error otherwise.
+func DefaultSubCommandRun(out io.Writer) func(c *cobra.Command, args []string) error {
+	return func(c *cobra.Command, args []string) error {
 		c.SetOutput(out)
-		RequireNoArguments(c, args)
-		c.Help()
-		CheckErr(ErrExit)
+		if err := RequireNoArguments(c, args); err != nil {
+			return err
+		}
+		if err := c.Help(); err != nil {
+			return err
+		}
+		return ErrExit
 	}
 }
 
-// RequireNoArguments exits with a usage error if extra arguments are provided.
-func RequireNoArguments(c *cobra.Command, args []string) {
+// RequireNoArguments returns a usage error if extra arguments are provided.
+func RequireNoArguments(c *cobra.Command, args []string) error {
 	if len(args) > 0 {
-		CheckErr(UsageErrorf(c, "unknown command %q", strings.Join(args, " ")))
+		return UsageErrorf(c, "unknown command %q", strings.Join(args, " "))
 	}
+	return nil
 }
 
-// UsageErrorf prints the usage error
+// UsageErrorf returns a fabricator.UsageError of cmd, which refers to its help
 func UsageErrorf(cmd *cobra.Command, format string, args ...interface{}) error {
 	msg := fmt.Sprintf(format, args...)
-	return fmt.Errorf("%s\nSee '%s -h' for help and examples", msg, cmd.CommandPath())
+	return &fabricator.UsageError{Err: fmt.Errorf("%s\nSee '%s -h' for help and examples", msg, cmd.CommandPath())}
 }
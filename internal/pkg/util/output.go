@@ -0,0 +1,54 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// OutputFormats are the machine-readable formats selected by the --output
+// flag.
+var OutputFormats = []string{"json", "yaml"}
+
+// AddOutputFlag adds the -o/--output flag to cmd, which selects one of
+// OutputFormats instead of the human-readable output.
+func AddOutputFlag(cmd *cobra.Command, output *string) {
+	cmd.Flags().StringVarP(output, "output", "o", *output, "Output format, one of json or yaml; human-readable text if not set")
+	_ = cmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions(OutputFormats, cobra.ShellCompDirectiveNoFileComp))
+}
+
+// ValidateOutputFormat returns a usage error of cmd unless output is empty or
+// one of OutputFormats.
+func ValidateOutputFormat(cmd *cobra.Command, output string) error {
+	if output == "" {
+		return nil
+	}
+	for _, format := range OutputFormats {
+		if output == format {
+			return nil
+		}
+	}
+	return UsageErrorf(cmd, "unsupported output format %q, must be json or yaml", output)
+}
+
+// PrintObject writes obj to w in format, one of OutputFormats.
+func PrintObject(w io.Writer, format string, obj interface{}) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(obj)
+	case "yaml":
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(obj); err != nil {
+			return err
+		}
+		return encoder.Close()
+	default:
+		return fmt.Errorf("unsupported output format %q", format)
+	}
+}
@@ -2,11 +2,15 @@ package cmd
 
 import (
 	"context"
-	"fmt"
+	"log/slog"
 	"os"
 	"strings"
 
+	"code.cestus.io/tools/fabricator/internal/pkg/util"
+	"code.cestus.io/tools/fabricator/pkg/cmd/completion"
+	"code.cestus.io/tools/fabricator/pkg/cmd/doctor"
 	"code.cestus.io/tools/fabricator/pkg/cmd/help"
+	"code.cestus.io/tools/fabricator/pkg/cmd/initialize"
 	"code.cestus.io/tools/fabricator/pkg/cmd/plugin"
 	"code.cestus.io/tools/fabricator/pkg/cmd/version"
 	"code.cestus.io/tools/fabricator/pkg/fabricator"
@@ -15,31 +19,73 @@ import (
 )
 
 // NewDefaultFabricatorCommand creates the `fabricator` command with default arguments
-func NewDefaultFabricatorCommand(ctx context.Context, io fabricator.IOStreams, flagParser fabricator.FlagParser) *cobra.Command {
-	return NewDefaultFabricatorCommandWithArgs(ctx, NewDefaultPluginHandler(plugin.ValidPluginFilenamePrefixes, io), os.Args, io, flagParser)
+func NewDefaultFabricatorCommand(ctx context.Context, io fabricator.IOStreams, flagParser fabricator.FlagParser, options ...Option) *cobra.Command {
+	return NewDefaultFabricatorCommandWithArgs(ctx, NewDefaultPluginHandler(plugin.ValidPluginFilenamePrefixes, io), os.Args, io, flagParser, options...)
 }
 
 // NewDefaultFabricatorCommandWithArgs creates the `fabricator` command with arguments
-func NewDefaultFabricatorCommandWithArgs(ctx context.Context, pluginHandler plugin.PluginHandler, args []string, io fabricator.IOStreams, flagparser fabricator.FlagParser) *cobra.Command {
-	cmd := NewFabricatorCommand(io, flagparser)
-
+func NewDefaultFabricatorCommandWithArgs(ctx context.Context, pluginHandler plugin.PluginHandler, args []string, io fabricator.IOStreams, flagparser fabricator.FlagParser, options ...Option) *cobra.Command {
 	if pluginHandler == nil {
-		return cmd
+		return NewFabricatorCommand(io, flagparser, options...)
 	}
+	cfg := newConfig(options)
+	pluginHandler = withGenerators(pluginHandler, io, cfg.generators)
+	cmd := newFabricatorCommand(io, flagparser, pluginHandler, cfg)
 
 	if len(args) > 1 {
 		cmdPathPieces := args[1:]
 
+		// When completing, the plugin is named by the arguments before the one
+		// being completed, if any.
+		if cmdPathPieces[0] == cobra.ShellCompRequestCmd || cmdPathPieces[0] == cobra.ShellCompNoDescRequestCmd {
+			if len(cmdPathPieces) > 1 {
+				cmdPathPieces = cmdPathPieces[1 : len(cmdPathPieces)-1]
+			} else {
+				cmdPathPieces = nil
+			}
+		}
+
 		// only look for suitable extension executables if
 		// the specified command does not already exist
-		if _, _, err := cmd.Find(cmdPathPieces); err != nil {
-			cmd.AddCommand(plugin.NewPluginWrapper(ctx, io, pluginHandler, flagparser, cmdPathPieces))
+		if len(cmdPathPieces) > 0 {
+			if _, _, err := cmd.Find(cmdPathPieces); err != nil {
+				wrapper := plugin.NewPluginWrapper(ctx, io, pluginHandler, flagparser, cmdPathPieces)
+				wrapper.GroupID = plugin.CommandGroupID
+				plugin.AddCommandGroup(cmd)
+				cmd.AddCommand(wrapper)
+			}
+		}
+
+		// help for a plugin is printed by the plugin.
+		if len(cmdPathPieces) > 1 && cmdPathPieces[0] == "help" {
+			if _, _, err := cmd.Find(cmdPathPieces[1:]); err != nil {
+				wrapper := plugin.NewPluginWrapper(ctx, io, pluginHandler, flagparser, append(append([]string{}, cmdPathPieces[1:]...), "--help"))
+				wrapper.Hidden = true
+				cmd.AddCommand(wrapper)
+			}
 		}
 	}
 
 	return cmd
 }
 
+// RootArgs returns the part of args, the commandline without the name of the
+// executable, which fabricator reads its own flags from: all of it for the
+// commands of fabricator, and only the arguments before the name of the plugin
+// otherwise, since the plugin's arguments are its own.
+func RootArgs(args []string, options ...Option) []string {
+	cmd := NewFabricatorCommand(fabricator.IOStreams{}, nil, options...)
+	if _, _, err := cmd.Find(args); err == nil {
+		return args
+	}
+	for i, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			return args[:i]
+		}
+	}
+	return args
+}
+
 type options struct {
 	fabricator.RootOptions
 	fabricator.IOStreams
@@ -54,14 +100,29 @@ func NewOptions(ioStreams fabricator.IOStreams, flagset *pflag.FlagSet) *options
 	return &o
 }
 
+// commandGroupID is the ID of the command group of the builtin commands.
+const commandGroupID = "commands"
+
 // NewFabricatorCommand creates the `fabricator` command and its nested children.
-func NewFabricatorCommand(io fabricator.IOStreams, flagparser fabricator.FlagParser) *cobra.Command {
+func NewFabricatorCommand(io fabricator.IOStreams, flagparser fabricator.FlagParser, options ...Option) *cobra.Command {
+	cfg := newConfig(options)
+	pluginHandler := withGenerators(NewDefaultPluginHandler(plugin.ValidPluginFilenamePrefixes, io), io, cfg.generators)
+	return newFabricatorCommand(io, flagparser, pluginHandler, cfg)
+}
+
+// newFabricatorCommand creates the `fabricator` command, whose commands look
+// up plugins with pluginHandler.
+func newFabricatorCommand(io fabricator.IOStreams, flagparser fabricator.FlagParser, pluginHandler plugin.PluginHandler, cfg *config) *cobra.Command {
 	// Parent command to which all subcommands are added.
 	cmds := &cobra.Command{
 		Use:   "fabricator",
 		Short: "fabricator is the swiss army knive for code generation",
 		Long:  `"fabricator is the swiss army knive for code generation"`,
-		Run:   runHelp,
+		RunE:  runHelp,
+		// Errors are returned by Execute, to be printed and mapped to an exit
+		// code by the caller.
+		SilenceErrors: true,
+		SilenceUsage:  true,
 		// Hook before and after Run
 		PersistentPreRunE: func(*cobra.Command, []string) error {
 			return nil
@@ -72,7 +133,18 @@ func NewFabricatorCommand(io fabricator.IOStreams, flagparser fabricator.FlagPar
 	}
 
 	flags := cmds.PersistentFlags()
-	_ = NewOptions(io, cmds.LocalFlags())
+	o := NewOptions(io, cmds.Flags())
+
+	// Complete the names of plugins, which are only added as commands when
+	// they are invoked.
+	cmds.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		_ = flagparser(cmd)
+		return plugin.CompletePluginNames(cmd, plugin.SearchPaths(o.PluginPath), args, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+	// Invalid flags of all commands are usage errors.
+	cmds.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return util.UsageErrorf(cmd, "%v", err)
+	})
 	flags.SetNormalizeFunc(WarnWordSepNormalizeFunc) // Warn for "_" flags
 
 	// Normalize all flags that are coming from other packages or pre-configurations
@@ -84,17 +156,40 @@ func NewFabricatorCommand(io fabricator.IOStreams, flagparser fabricator.FlagPar
 	// From this point and forward we get warnings on flags that contain "_" separators
 	cmds.SetGlobalNormalizationFunc(WarnWordSepNormalizeFunc)
 
-	cmds.AddCommand(plugin.NewCmdPlugin(io, flagparser))
-	cmds.AddCommand(version.NewCmdVersion(io))
+	// The group of plugin commands is added along with the first of them.
+	cmds.AddGroup(&cobra.Group{ID: commandGroupID, Title: "Available Commands:"})
+
 	help := help.NewHelpCommand(io)
-	cmds.AddCommand(help)
+	for _, cmd := range []*cobra.Command{
+		plugin.NewCmdPlugin(io, flagparser),
+		version.NewCmdVersion(io),
+		completion.NewCmdCompletion(io),
+		doctor.NewCmdDoctor(io, flagparser, pluginHandler),
+		initialize.NewCmdInit(io, flagparser),
+		help,
+	} {
+		cmd.GroupID = commandGroupID
+		cmds.AddCommand(cmd)
+	}
 	cmds.SetHelpCommand(help)
+	addGeneratorCommands(cmds, io, pluginHandler, flagparser, cfg.generators)
+
+	// List the plugins in the usage, and so the help, of the root command.
+	// They are only discovered when it is shown, and their commands removed
+	// afterwards, as they do not run the plugins.
+	usage := cmds.UsageFunc()
+	cmds.SetUsageFunc(func(cmd *cobra.Command) error {
+		if cmd == cmd.Root() {
+			defer cmd.RemoveCommand(plugin.AddPluginCommands(cmd, plugin.LookupPaths(flagparser), plugin.DefaultDescriptionCacheFile())...)
+		}
+		return usage(cmd)
+	})
 
 	return cmds
 }
 
-func runHelp(cmd *cobra.Command, args []string) {
-	cmd.Help()
+func runHelp(cmd *cobra.Command, args []string) error {
+	return cmd.Help()
 }
 
 // WarnWordSepNormalizeFunc changes and warns for flags that contain "_" separators
@@ -102,7 +197,7 @@ func WarnWordSepNormalizeFunc(f *pflag.FlagSet, name string) pflag.NormalizedNam
 	if strings.Contains(name, "_") {
 		nname := strings.Replace(name, "_", "-", -1)
 		if _, alreadyWarned := underscoreWarnings[name]; !alreadyWarned {
-			fmt.Printf("using an underscore in a flag name is not supported. %s has been converted to %s.", name, nname)
+			slog.Warn("using an underscore in a flag name is not supported", "flag", name, "converted", nname)
 			underscoreWarnings[name] = true
 		}
 
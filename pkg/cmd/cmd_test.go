@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 
-	"code.cestus.io/tools/fabricator/internal/pkg/util"
+	"code.cestus.io/tools/fabricator/pkg/cmd/plugin"
 	"code.cestus.io/tools/fabricator/pkg/fabricator"
 	"code.cestus.io/tools/fabricator/pkg/helpers"
 )
@@ -15,8 +17,10 @@ func TestFabricatorCommandHandlesPlugins(t *testing.T) {
 	tests := []struct {
 		name             string
 		args             []string
+		env              map[string]string
 		expectPlugin     string
 		expectPluginArgs []string
+		expectPluginEnv  fabricator.Environment
 		expectError      string
 	}{
 		{
@@ -24,6 +28,21 @@ func TestFabricatorCommandHandlesPlugins(t *testing.T) {
 			args:             []string{"fabricator", "foo", "--bar"},
 			expectPlugin:     "plugin/testdata/fabricator-foo",
 			expectPluginArgs: []string{"--bar"},
+			expectPluginEnv:  fabricator.Environment{fabricator.VerbosityEnv: "0", fabricator.LogFormatEnv: "text"},
+		},
+		{
+			name:            "test that the logging is passed on to a plugin",
+			args:            []string{"fabricator", "foo"},
+			env:             map[string]string{"FABRICATOR_VERBOSITY": "2", "FABRICATOR_LOG_FORMAT": "json"},
+			expectPlugin:    "plugin/testdata/fabricator-foo",
+			expectPluginEnv: fabricator.Environment{fabricator.VerbosityEnv: "2", fabricator.LogFormatEnv: "json"},
+		},
+		{
+			name:             "test that the flags of a plugin are not read by fabricator",
+			args:             []string{"fabricator", "foo", "x", "-v", "2"},
+			expectPlugin:     "plugin/testdata/fabricator-foo",
+			expectPluginArgs: []string{"x", "-v", "2"},
+			expectPluginEnv:  fabricator.Environment{fabricator.VerbosityEnv: "0", fabricator.LogFormatEnv: "text"},
 		},
 		{
 			name: "test that a plugin does not execute over an existing command by the same name",
@@ -33,15 +52,14 @@ func TestFabricatorCommandHandlesPlugins(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
+			for k, v := range test.env {
+				t.Setenv(k, v)
+			}
 			ctx := context.Background()
 			pluginsHandler := &testPluginHandler{
 				pluginsDirectory: "plugin/testdata",
 			}
-			io, _, _, errOut := fabricator.NewTestIOStreams()
-
-			util.BehaviorOnFatal(func(str string, code int) {
-				errOut.Write([]byte(str))
-			})
+			io, _, _, _ := fabricator.NewTestIOStreams()
 
 			root := NewDefaultFabricatorCommandWithArgs(ctx, pluginsHandler, test.args, io, helpers.DefaultFlagParser)
 
@@ -61,6 +79,47 @@ func TestFabricatorCommandHandlesPlugins(t *testing.T) {
 			if len(pluginsHandler.withArgs) != len(test.expectPluginArgs) {
 				t.Fatalf("unexpected plugin execution args: expected %q, got %q", test.expectPluginArgs, pluginsHandler.withArgs)
 			}
+
+			if test.expectPluginEnv != nil && !reflect.DeepEqual(pluginsHandler.withEnv, test.expectPluginEnv) {
+				t.Fatalf("unexpected plugin execution env: expected %v, got %v", test.expectPluginEnv, pluginsHandler.withEnv)
+			}
+		})
+	}
+}
+
+func TestRootArgs(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		expect []string
+	}{
+		{
+			name:   "all arguments of a command are fabricator's",
+			args:   []string{"doctor", "-vv", "--fabfile", "fab.yml"},
+			expect: []string{"doctor", "-vv", "--fabfile", "fab.yml"},
+		},
+		{
+			name:   "all arguments of fabricator are fabricator's",
+			args:   []string{"-v", "--help"},
+			expect: []string{"-v", "--help"},
+		},
+		{
+			name:   "the arguments of a plugin are the plugin's",
+			args:   []string{"foo", "x", "-v", "2"},
+			expect: []string{},
+		},
+		{
+			name:   "flags before the name of a plugin are fabricator's",
+			args:   []string{"-v", "foo", "-v"},
+			expect: []string{"-v"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if args := RootArgs(test.args); !reflect.DeepEqual(args, test.expect) {
+				t.Errorf("want %q, have %q", test.expect, args)
+			}
 		})
 	}
 }
@@ -114,3 +173,102 @@ func (h *testPluginHandler) Execute(ctx context.Context, executablePath string,
 	h.withEnv = env
 	return nil
 }
+
+func TestDefaultPluginHandlerExecute(t *testing.T) {
+	runner := helpers.NewFakeRunner().
+		Script([]helpers.FakeCommand{{Stdout: "generated\n", Stderr: "warning\n"}}, "plugin/testdata/fabricator-foo", "--bar")
+	ctx := helpers.ContextWithRunner(context.Background(), runner)
+	io, _, out, errOut := fabricator.NewTestIOStreams()
+
+	handler := NewDefaultPluginHandler(plugin.ValidPluginFilenamePrefixes, io)
+	err := handler.Execute(ctx, "plugin/testdata/fabricator-foo", []string{"--bar"}, fabricator.Environment{"FABRICATOR_ROOTDIR": "out"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	invocations := runner.Invocations()
+	if len(invocations) != 1 {
+		t.Fatalf("want one plugin execution, have %q", invocations)
+	}
+	if rootdir := invocations[0].Getenv("FABRICATOR_ROOTDIR"); rootdir != "out" {
+		t.Errorf("want FABRICATOR_ROOTDIR=out passed to the plugin, have %q", rootdir)
+	}
+	if !strings.HasSuffix(out.String(), "generated\n") {
+		t.Errorf("want plugin stdout relayed, have %q", out)
+	}
+	if errOut.String() != "warning\n" {
+		t.Errorf("want plugin stderr relayed, have %q", errOut)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		pluginExit int
+		expectCode int
+	}{
+		{
+			name:       "a failed plugin exits with its exit code",
+			args:       []string{"fabricator", "foo", "--bar"},
+			pluginExit: 42,
+			expectCode: 42,
+		},
+		{
+			name:       "a missing plugin exits with 127",
+			args:       []string{"fabricator", "nosuch"},
+			expectCode: fabricator.ExitPluginNotFound,
+		},
+		{
+			name:       "an unknown flag is a usage error",
+			args:       []string{"fabricator", "version", "--bogus"},
+			expectCode: fabricator.ExitUsage,
+		},
+		{
+			name:       "an invalid flag value is a usage error",
+			args:       []string{"fabricator", "version", "-o", "xml"},
+			expectCode: fabricator.ExitUsage,
+		},
+		{
+			name:       "help for a missing plugin exits with 127",
+			args:       []string{"fabricator", "help", "nosuch"},
+			expectCode: fabricator.ExitPluginNotFound,
+		},
+		{
+			name:       "a missing argument is a usage error",
+			args:       []string{"fabricator", "completion"},
+			expectCode: fabricator.ExitUsage,
+		},
+		{
+			name:       "a command without subcommand prints its help and fails",
+			args:       []string{"fabricator", "plugin"},
+			expectCode: fabricator.ExitError,
+		},
+		{
+			name:       "a command succeeds",
+			args:       []string{"fabricator", "version"},
+			expectCode: fabricator.ExitOK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runner := helpers.NewFakeRunner().
+				Script([]helpers.FakeCommand{{ExitCode: test.pluginExit}}, "plugin/testdata/fabricator-foo", "--bar")
+			ctx := helpers.ContextWithRunner(context.Background(), runner)
+			io := fabricator.NewTestIOStreamsDiscard()
+			handler := &DefaultPluginHandler{ValidPrefixes: plugin.ValidPluginFilenamePrefixes, IO: io}
+			t.Setenv("FABRICATOR_PLUGIN_PATH", "plugin/testdata")
+
+			root := NewDefaultFabricatorCommandWithArgs(ctx, handler, test.args, io, helpers.DefaultFlagParser)
+			root.SetArgs(test.args[1:])
+			root.SetOut(io.Out)
+			root.SetErr(io.ErrOut)
+			err := root.ExecuteContext(ctx)
+
+			if code := fabricator.ExitCode(err); code != test.expectCode {
+				t.Errorf("want exit code %d, have %d for %v", test.expectCode, code, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package completion
+
+import (
+	"code.cestus.io/tools/fabricator/internal/pkg/util"
+	"code.cestus.io/tools/fabricator/pkg/fabricator"
+	"github.com/spf13/cobra"
+)
+
+var completionLong = `
+		Output shell completion code for the specified shell (bash, zsh, fish or powershell).
+		The shell code must be evaluated to provide interactive completion of fabricator
+		commands, of the plugins found on the plugin path and PATH, and of the arguments of
+		plugins which support cobra's completion protocol.
+
+		Bash (requires the bash-completion package):
+		  source <(fabricator completion bash)
+
+		Zsh:
+		  source <(fabricator completion zsh)
+
+		Fish:
+		  fabricator completion fish | source
+
+		PowerShell:
+		  fabricator completion powershell | Out-String | Invoke-Expression`
+
+type options struct {
+	fabricator.IOStreams
+	cmd *cobra.Command
+}
+
+// NewOptions returns initialized Options
+func NewOptions(ioStreams fabricator.IOStreams) *options {
+	return &options{
+		IOStreams: ioStreams,
+	}
+}
+
+// Run writes the completion script for shell
+func (o *options) Run(shell string) error {
+	root := o.cmd.Root()
+	switch shell {
+	case "bash":
+		return root.GenBashCompletionV2(o.Out, true)
+	case "zsh":
+		return root.GenZshCompletion(o.Out)
+	case "fish":
+		return root.GenFishCompletion(o.Out, true)
+	case "powershell":
+		return root.GenPowerShellCompletionWithDesc(o.Out)
+	default:
+		return util.UsageErrorf(o.cmd, "unsupported shell %q", shell)
+	}
+}
+
+// NewCmdCompletion creates the completion command
+func NewCmdCompletion(ioStreams fabricator.IOStreams) *cobra.Command {
+	o := NewOptions(ioStreams)
+	cmd := &cobra.Command{
+		Use:                   "completion bash|zsh|fish|powershell",
+		DisableFlagsInUseLine: true,
+		Short:                 "Output shell completion code for the specified shell",
+		Long:                  completionLong,
+		ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return util.UsageErrorf(cmd, "expected one shell, got %d arguments", len(args))
+			}
+			return o.Run(args[0])
+		},
+	}
+	o.cmd = cmd
+	return cmd
+}
@@ -0,0 +1,163 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"code.cestus.io/tools/fabricator/pkg/cmd/plugin"
+	"code.cestus.io/tools/fabricator/pkg/fabricator"
+	"code.cestus.io/tools/fabricator/pkg/ff"
+	"code.cestus.io/tools/fabricator/pkg/ff/ffpflag"
+	"github.com/spf13/cobra"
+)
+
+// writePlugins writes plugin scripts to a new directory on the plugin path,
+// which is the only one searched. Scripts are executable unless their name
+// contains "notanexec".
+func writePlugins(t *testing.T, plugins map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, script := range plugins {
+		perm := os.FileMode(0o755)
+		if strings.Contains(name, "notanexec") {
+			perm = 0o644
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script+"\n"), perm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("FABRICATOR_PLUGIN_PATH", dir)
+	t.Setenv("PATH", "")
+}
+
+// envFlagParser only reads flags from the environment, as the arguments of
+// the test binary are no fabricator flags.
+func envFlagParser(cmd *cobra.Command) error {
+	return ff.Parse(ffpflag.NewFlagSet(cmd.Flags()), nil, ff.WithEnvVarPrefix("fabricator"))
+}
+
+// executeFabricator runs fabricator with args and returns its output.
+func executeFabricator(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	io, _, out, _ := fabricator.NewTestIOStreams()
+	args = append([]string{"fabricator"}, args...)
+	handler := NewDefaultPluginHandler(plugin.ValidPluginFilenamePrefixes, io)
+
+	root := NewDefaultFabricatorCommandWithArgs(context.Background(), handler, args, io, envFlagParser)
+	var stdout bytes.Buffer
+	root.SetOut(&stdout)
+	root.SetArgs(args[1:])
+	err := root.Execute()
+	return stdout.String() + out.String(), err
+}
+
+func TestCompletion(t *testing.T) {
+	writePlugins(t, map[string]string{
+		// fabricator-foo understands cobra's completion protocol and opts in
+		// to completion, fabricator-nocomplete does not opt in.
+		"fabricator-foo":           `if [ "$1" = __complete ]; then shift; echo "alpha	first"; echo "args:$*"; echo ":4"; exit 0; fi`,
+		"fabricator_complete-foo":  `exec "${0%/*}/fabricator-foo" __complete "$@"`,
+		"fabricator-nocomplete":    `echo "alpha	first"; echo ":4"`,
+		"fabricator-slow":          `echo slow`,
+		"fabricator_complete-slow": `/bin/sleep 30; echo ":4"`,
+		"fabricator-foo-bar":       `echo bar`,
+		"fabricator-baz_qux":       `echo baz`,
+		"fabricator-version":       `echo version`,
+		"fabricator-notanexec":     ``,
+	})
+
+	tests := []struct {
+		name      string
+		args      []string
+		expect    []string
+		notExpect []string
+		expectErr bool
+	}{
+		{
+			name:      "plugin names are completed with the commands",
+			args:      []string{cobra.ShellCompRequestCmd, ""},
+			expect:    []string{"version\t", "foo\tplugin fabricator-foo", "baz-qux\tplugin fabricator-baz_qux", ":4"},
+			notExpect: []string{"version\tplugin", "notanexec"},
+		},
+		{
+			name:      "plugin names are completed by prefix",
+			args:      []string{cobra.ShellCompRequestCmd, "b"},
+			expect:    []string{"baz-qux\tplugin fabricator-baz_qux"},
+			notExpect: []string{"foo"},
+		},
+		{
+			name:   "plugin arguments are completed with nested plugin names and by the plugin",
+			args:   []string{cobra.ShellCompRequestCmd, "foo", ""},
+			expect: []string{"bar\tplugin fabricator-foo-bar", "alpha\tfirst", "args:\n", ":4"},
+		},
+		{
+			name:      "plugin arguments after flags are completed by the plugin",
+			args:      []string{cobra.ShellCompRequestCmd, "foo", "--x", ""},
+			expect:    []string{"alpha\tfirst", "args:--x\n", ":4"},
+			notExpect: []string{"bar"},
+		},
+		{
+			name:   "plugin arguments fall back to file names for other plugins",
+			args:   []string{cobra.ShellCompRequestCmd, "foo", "bar", ""},
+			expect: []string{":0"},
+		},
+		{
+			name:      "plugins are not run to complete their arguments without opting in",
+			args:      []string{cobra.ShellCompRequestCmd, "nocomplete", ""},
+			expect:    []string{":0"},
+			notExpect: []string{"alpha"},
+		},
+		{
+			name:   "completion executables which do not answer in time are stopped",
+			args:   []string{cobra.ShellCompRequestCmd, "slow", ""},
+			expect: []string{":0"},
+		},
+		{
+			name:      "completing without arguments is rejected",
+			args:      []string{cobra.ShellCompRequestCmd},
+			expectErr: true,
+		},
+		{
+			name:      "completing without arguments and descriptions is rejected",
+			args:      []string{cobra.ShellCompNoDescRequestCmd},
+			expectErr: true,
+		},
+		{
+			name:   "shell completion code is written",
+			args:   []string{"completion", "bash"},
+			expect: []string{"__start_fabricator"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output, err := executeFabricator(t, test.args...)
+			if test.expectErr {
+				if err == nil {
+					t.Fatalf("want an error, have output %q", output)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for _, s := range test.expect {
+				if !strings.Contains(output, s) {
+					t.Errorf("want %q in output, have %q", s, output)
+				}
+			}
+			for _, s := range test.notExpect {
+				if strings.Contains(output, s) {
+					t.Errorf("want no %q in output, have %q", s, output)
+				}
+			}
+		})
+	}
+}
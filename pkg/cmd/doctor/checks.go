@@ -0,0 +1,203 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"code.cestus.io/tools/fabricator/pkg/cmd/plugin"
+	"code.cestus.io/tools/fabricator/pkg/fabricator"
+	"code.cestus.io/tools/fabricator/pkg/helpers"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// checkFabfile checks that the fab-file at path exists and is valid. It
+// returns the config read, or nil if it could not be read.
+func checkFabfile(path string) (*fabricator.FabricatorConfig, []Result) {
+	const check = "fabfile"
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, []Result{{check, Fail, fmt.Sprintf("no fab-file at %s", path)}}
+	} else if err != nil {
+		return nil, []Result{{check, Fail, err.Error()}}
+	}
+
+	var config fabricator.FabricatorConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, []Result{{check, Fail, fmt.Sprintf("%s is invalid: %v", path, err)}}
+	}
+
+	var results []Result
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&fabricator.FabricatorConfig{}); err != nil {
+		message := err.Error()
+		var typeErr *yaml.TypeError
+		if errors.As(err, &typeErr) {
+			message = strings.Join(typeErr.Errors, "; ")
+		}
+		results = append(results, Result{check, Warn, fmt.Sprintf("%s has unknown fields: %s", path, message)})
+	}
+	if config.ApiVersion == "" {
+		results = append(results, Result{check, Warn, fmt.Sprintf("%s has no apiVersion", path)})
+	}
+	if config.Kind == "" {
+		results = append(results, Result{check, Warn, fmt.Sprintf("%s has no kind", path)})
+	}
+
+	failed := false
+	// A component may be generated by several generators.
+	seen := map[[2]string]bool{}
+	for i, component := range config.Components {
+		key := [2]string{component.Name, component.Generator}
+		switch {
+		case component.Name == "":
+			results = append(results, Result{check, Fail, fmt.Sprintf("component %d of %s has no name", i+1, path)})
+			failed = true
+		case seen[key]:
+			results = append(results, Result{check, Fail, fmt.Sprintf("component %q of %s is defined more than once for generator %q", component.Name, path, component.Generator)})
+			failed = true
+		}
+		seen[key] = true
+
+		if component.Generator == "" {
+			results = append(results, Result{check, Fail, fmt.Sprintf("component %q of %s has no generator", component.Name, path)})
+			failed = true
+		}
+	}
+
+	if !failed {
+		results = append([]Result{{check, Pass, fmt.Sprintf("%s defines %d components", path, len(config.Components))}}, results...)
+	}
+	return &config, results
+}
+
+// checkRootDirectory checks that files can be created in dir.
+func checkRootDirectory(dir string) Result {
+	const check = "rootdir"
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return Result{check, Fail, err.Error()}
+	}
+	if !info.IsDir() {
+		return Result{check, Fail, fmt.Sprintf("%s is not a directory", dir)}
+	}
+
+	f, err := os.CreateTemp(dir, ".fabricator-doctor-*")
+	if err != nil {
+		return Result{check, Fail, fmt.Sprintf("%s is not writable: %v", dir, err)}
+	}
+	f.Close()
+	if err := os.Remove(f.Name()); err != nil {
+		return Result{check, Warn, fmt.Sprintf("%s is writable, but removing %s failed: %v", dir, f.Name(), err)}
+	}
+	return Result{check, Pass, fmt.Sprintf("%s is writable", dir)}
+}
+
+// checkGenerators checks that the generator of every component of config is
+// found by handler.
+func checkGenerators(ctx context.Context, handler plugin.PluginHandler, paths []string, config *fabricator.FabricatorConfig) []Result {
+	const check = "generator"
+	if config == nil {
+		return nil
+	}
+
+	var generators []string
+	components := map[string][]string{}
+	for i, component := range config.Components {
+		if component.Generator == "" {
+			continue
+		}
+		if _, ok := components[component.Generator]; !ok {
+			generators = append(generators, component.Generator)
+		}
+		name := component.Name
+		if name == "" {
+			name = fmt.Sprintf("unnamed component %d", i+1)
+		}
+		components[component.Generator] = append(components[component.Generator], name)
+	}
+
+	var results []Result
+	for _, generator := range generators {
+		name := generator
+		for _, prefix := range plugin.ValidPluginFilenamePrefixes {
+			name = strings.TrimPrefix(name, prefix+"-")
+		}
+
+		if path, found := plugin.LookupPlugin(ctx, handler, name, paths); found {
+			results = append(results, Result{check, Pass, fmt.Sprintf("%s is provided by %s", generator, path)})
+		} else {
+			results = append(results, Result{check, Fail, fmt.Sprintf("%s, used by %s, is not found on the plugin path or PATH", generator, strings.Join(components[generator], ", "))})
+		}
+	}
+	return results
+}
+
+// checkPlugins checks that the plugins in paths are executable and not
+// shadowed.
+func checkPlugins(root *cobra.Command, paths []string) []Result {
+	const check = "plugins"
+
+	plugins, _ := plugin.DiscoverPlugins(paths)
+	verifier := plugin.NewCommandOverrideVerifier(root)
+
+	var results []Result
+	for _, p := range plugins {
+		for _, err := range verifier.Verify(p.Path) {
+			status, message := Warn, err.Error()
+			if trimmed, ok := strings.CutPrefix(message, "error: "); ok {
+				status, message = Fail, trimmed
+			}
+			results = append(results, Result{check, status, strings.TrimPrefix(message, "warning: ")})
+		}
+	}
+
+	if len(results) == 0 {
+		return []Result{{check, Pass, fmt.Sprintf("%d plugins found", len(plugins))}}
+	}
+	return results
+}
+
+// checkGo checks for the Go toolchain, and the Go module and workspace of
+// dir.
+func checkGo(ctx context.Context, dir string) []Result {
+	var results []Result
+
+	version, err := helpers.NewExecutor(dir, fabricator.IOStreams{}).Output(ctx, "go", "env", "GOVERSION")
+	switch {
+	case errors.Is(err, exec.ErrNotFound):
+		results = append(results, Result{"go", Warn, "the go command is not found on PATH; Go generators will not work"})
+	case err != nil:
+		results = append(results, Result{"go", Fail, err.Error()})
+	default:
+		results = append(results, Result{"go", Pass, strings.TrimSpace(version)})
+	}
+
+	mod, err := helpers.FindGoModule(dir)
+	switch {
+	case errors.Is(err, helpers.ErrNoGoModule):
+		results = append(results, Result{"go module", Warn, fmt.Sprintf("%s is not in a Go module", dir)})
+	case err != nil:
+		results = append(results, Result{"go module", Fail, err.Error()})
+	default:
+		results = append(results, Result{"go module", Pass, fmt.Sprintf("%s in %s", mod.Path, mod.Dir)})
+	}
+
+	ws, err := helpers.FindGoWorkspace(dir)
+	switch {
+	case errors.Is(err, helpers.ErrNoGoWorkspace):
+	case err != nil:
+		results = append(results, Result{"go workspace", Fail, err.Error()})
+	default:
+		results = append(results, Result{"go workspace", Pass, fmt.Sprintf("%s uses %d modules", ws.GoWork, len(ws.Modules))})
+	}
+	return results
+}
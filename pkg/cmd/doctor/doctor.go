@@ -0,0 +1,170 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"code.cestus.io/tools/fabricator/internal/pkg/util"
+	"code.cestus.io/tools/fabricator/pkg/cmd/plugin"
+	"code.cestus.io/tools/fabricator/pkg/fabricator"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var doctorLong = `
+		Check the environment fabricator runs in: the fab-file, the root directory, the
+		generators of all components, the installed plugins and the Go toolchain.
+
+		Every check passes, warns or fails; the command fails if any check fails.`
+
+// Status is the outcome of a check.
+type Status int
+
+const (
+	// Pass means nothing needs to be done.
+	Pass Status = iota
+	// Warn means fabricator works, but possibly not as expected.
+	Warn
+	// Fail means fabricator does not work.
+	Fail
+)
+
+func (s Status) String() string {
+	switch s {
+	case Pass:
+		return "pass"
+	case Warn:
+		return "warn"
+	case Fail:
+		return "fail"
+	default:
+		return fmt.Sprintf("Status(%d)", int(s))
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Status) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Result is the result of a check, as printed by the doctor command with
+// --output. The field names are stable.
+type Result struct {
+	// Check names what was checked, e.g. "fabfile".
+	Check string `json:"check" yaml:"check"`
+	// Status is "pass", "warn" or "fail".
+	Status Status `json:"status" yaml:"status"`
+	// Message describes the result.
+	Message string `json:"message" yaml:"message"`
+}
+
+// Report is the output of the doctor command with --output.
+type Report struct {
+	Results []Result `json:"results" yaml:"results"`
+}
+
+type options struct {
+	fabricator.RootOptions
+	fabricator.IOStreams
+	Output string
+
+	handler plugin.PluginHandler
+	root    *cobra.Command
+}
+
+// NewOptions returns initialized Options
+func NewOptions(ioStreams fabricator.IOStreams, flagset *pflag.FlagSet, flagparser fabricator.FlagParser, handler plugin.PluginHandler) *options {
+	o := options{
+		IOStreams: ioStreams,
+		handler:   handler,
+	}
+	o.RootOptions.FlagParser = flagparser
+	o.RootOptions.RegisterOptions(flagset)
+	return &o
+}
+
+// Complete reads the flags of cmd
+func (o *options) Complete(cmd *cobra.Command) error {
+	if err := o.FlagParser(cmd); err != nil {
+		return err
+	}
+	o.root = cmd.Root()
+	return nil
+}
+
+// Check runs all checks.
+func (o *options) Check(ctx context.Context) []Result {
+	var results []Result
+	config, configResults := checkFabfile(o.FabricatorFile)
+	results = append(results, configResults...)
+	results = append(results, checkRootDirectory(o.RootDirectory))
+
+	paths := plugin.SearchPaths(o.PluginPath)
+	results = append(results, checkGenerators(ctx, o.handler, paths, config)...)
+	results = append(results, checkPlugins(o.root, paths)...)
+	results = append(results, checkGo(ctx, o.RootDirectory)...)
+	return results
+}
+
+// Run runs all checks and prints their results. It fails with a
+// fabricator.ConfigError if any check fails.
+func (o *options) Run(ctx context.Context) error {
+	results := o.Check(ctx)
+
+	if o.Output != "" {
+		if err := util.PrintObject(o.Out, o.Output, Report{Results: results}); err != nil {
+			return err
+		}
+	} else {
+		w := tabwriter.NewWriter(o.Out, 0, 4, 2, ' ', 0)
+		for _, result := range results {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", strings.ToUpper(result.Status.String()), result.Check, result.Message)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Status == Fail {
+			failed++
+		}
+	}
+	switch failed {
+	case 0:
+		return nil
+	case 1:
+		return &fabricator.ConfigError{Err: fmt.Errorf("error: one check failed")}
+	default:
+		return &fabricator.ConfigError{Err: fmt.Errorf("error: %d checks failed", failed)}
+	}
+}
+
+// NewCmdDoctor creates the doctor command, which looks up generators with
+// handler
+func NewCmdDoctor(ioStreams fabricator.IOStreams, flagparser fabricator.FlagParser, handler plugin.PluginHandler) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "doctor",
+		Short:   "Check the environment fabricator runs in",
+		Long:    doctorLong,
+		Example: "  fabricator doctor --fabfile ./.fabricator.yml",
+	}
+	o := NewOptions(ioStreams, cmd.Flags(), flagparser, handler)
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if err := util.RequireNoArguments(cmd, args); err != nil {
+			return err
+		}
+		if err := o.Complete(cmd); err != nil {
+			return err
+		}
+		if err := util.ValidateOutputFormat(cmd, o.Output); err != nil {
+			return err
+		}
+		return o.Run(cmd.Context())
+	}
+	util.AddOutputFlag(cmd, &o.Output)
+	return cmd
+}
@@ -0,0 +1,145 @@
+package doctor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"code.cestus.io/tools/fabricator/pkg/fabricator"
+	"code.cestus.io/tools/fabricator/pkg/helpers"
+	"github.com/spf13/cobra"
+)
+
+// fakePluginHandler finds the plugins in plugins.
+type fakePluginHandler struct {
+	plugins map[string]string
+}
+
+func (h *fakePluginHandler) Lookup(ctx context.Context, filename string, paths []string) (string, bool) {
+	path, ok := h.plugins[filename]
+	return path, ok
+}
+
+func (h *fakePluginHandler) Execute(ctx context.Context, executablePath string, cmdArgs []string, environment fabricator.Environment) error {
+	return nil
+}
+
+func TestDoctor(t *testing.T) {
+	tests := []struct {
+		name      string
+		fabfile   string
+		goVersion string
+		expect    []Result
+		expectErr string
+	}{
+		{
+			name: "all checks pass",
+			fabfile: `apiVersion: fabricator.cestus.io/v1alpha1
+kind: Config
+components:
+  - name: api
+    generator: fabricator-foo
+  - name: api
+    generator: foo
+`,
+			goVersion: "go1.23.0\n",
+			expect: []Result{
+				{"fabfile", Pass, "defines 2 components"},
+				{"rootdir", Pass, "is writable"},
+				{"generator", Pass, "fabricator-foo is provided by /plugins/fabricator-foo"},
+				{"generator", Pass, "foo is provided by /plugins/fabricator-foo"},
+				{"plugins", Pass, "0 plugins found"},
+				{"go", Pass, "go1.23.0"},
+				{"go module", Pass, "example.com/project in"},
+			},
+		},
+		{
+			name:      "missing fabfile fails",
+			expect:    []Result{{"fabfile", Fail, "no fab-file at"}},
+			expectErr: "error: one check failed",
+		},
+		{
+			name:      "invalid fabfile fails",
+			fabfile:   `components: [`,
+			expect:    []Result{{"fabfile", Fail, "is invalid"}},
+			expectErr: "error: one check failed",
+		},
+		{
+			name: "fabfile problems are reported",
+			fabfile: `components:
+  - name: api
+    generator: foo
+    unknown: true
+  - name: api
+    generator: foo
+  - generator: bar
+  - name: api
+`,
+			expect: []Result{
+				{"fabfile", Warn, "unknown fields: line 4: field unknown not found"},
+				{"fabfile", Warn, "has no apiVersion"},
+				{"fabfile", Warn, "has no kind"},
+				{"fabfile", Fail, `component "api" of`},
+				{"fabfile", Fail, `is defined more than once for generator "foo"`},
+				{"fabfile", Fail, "component 3 of"},
+				{"fabfile", Fail, `component "api" of`},
+				{"generator", Pass, "foo is provided by"},
+				{"generator", Fail, "bar, used by unnamed component 3, is not found"},
+			},
+			expectErr: "error: 4 checks failed",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/project\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			fabfile := filepath.Join(dir, ".fabricator.yml")
+			if test.fabfile != "" {
+				if err := os.WriteFile(fabfile, []byte(test.fabfile), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			runner := helpers.NewFakeRunner().Reply(test.goVersion, "go", "env", "GOVERSION")
+			ctx := helpers.ContextWithRunner(context.Background(), runner)
+			io, _, out, _ := fabricator.NewTestIOStreams()
+			o := &options{
+				RootOptions: fabricator.RootOptions{FabricatorFile: fabfile, RootDirectory: dir, PluginPath: t.TempDir()},
+				IOStreams:   io,
+				handler:     &fakePluginHandler{plugins: map[string]string{"foo": "/plugins/fabricator-foo"}},
+				root:        &cobra.Command{Use: "fabricator"},
+			}
+			t.Setenv("PATH", "")
+			t.Setenv("GOWORK", "off")
+
+			err := o.Run(ctx)
+			if test.expectErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			} else if test.expectErr != "" && (err == nil || err.Error() != test.expectErr) {
+				t.Fatalf("want error %q, have %v", test.expectErr, err)
+			}
+
+			results := o.Check(ctx)
+			for _, want := range test.expect {
+				found := false
+				for _, result := range results {
+					if result.Check == want.Check && result.Status == want.Status && strings.Contains(result.Message, want.Message) {
+						found = true
+						break
+					}
+				}
+				if !found {
+					t.Errorf("want %s result %q for %s, have %+v", want.Status, want.Message, want.Check, results)
+				}
+			}
+			if !strings.Contains(out.String(), "PASS  rootdir") {
+				t.Errorf("want results printed, have %q", out)
+			}
+		})
+	}
+}
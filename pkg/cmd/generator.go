@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"code.cestus.io/tools/fabricator/pkg/cmd/plugin"
+	"code.cestus.io/tools/fabricator/pkg/fabricator"
+	"github.com/spf13/cobra"
+)
+
+// Generator is a generator built into the fabricator command, see
+// WithGenerators. It is run like its plugin would be, but in-process.
+type Generator interface {
+	// Run runs the generator with the arguments and flags following its name
+	// on the commandline.
+	Run(ctx context.Context, io fabricator.IOStreams, args []string) error
+}
+
+// GeneratorFunc adapts a function to a Generator.
+type GeneratorFunc func(ctx context.Context, io fabricator.IOStreams, args []string) error
+
+// Run implements Generator
+func (f GeneratorFunc) Run(ctx context.Context, io fabricator.IOStreams, args []string) error {
+	return f(ctx, io, args)
+}
+
+// describer is implemented by generators with a description for the help of
+// the fabricator command.
+type describer interface {
+	Description() string
+}
+
+// commandGenerator runs a command as Generator.
+type commandGenerator struct {
+	newCommand func(fabricator.IOStreams, fabricator.FlagParser) *cobra.Command
+	flagparser fabricator.FlagParser
+}
+
+// CommandGenerator returns a Generator which runs the command created by
+// newCommand with flagparser, e.g. the NewCommand of a plugin created by
+// `fabricator plugin new`, so the plugin can be linked into the fabricator
+// command.
+func CommandGenerator(newCommand func(fabricator.IOStreams, fabricator.FlagParser) *cobra.Command, flagparser fabricator.FlagParser) Generator {
+	return &commandGenerator{newCommand: newCommand, flagparser: flagparser}
+}
+
+// Run implements Generator. The flag parser reads args rather than the
+// commandline of fabricator, see fabricator.ArgsFromContext.
+func (g *commandGenerator) Run(ctx context.Context, io fabricator.IOStreams, args []string) error {
+	ctx = fabricator.ContextWithArgs(ctx, args)
+	cmd := g.newCommand(io, g.flagparser)
+	cmd.SetContext(ctx)
+	cmd.SetArgs(args)
+	cmd.SetIn(io.In)
+	cmd.SetOut(io.Out)
+	cmd.SetErr(io.ErrOut)
+	return cmd.ExecuteContext(ctx)
+}
+
+// Description returns the short description of the command.
+func (g *commandGenerator) Description() string {
+	return g.newCommand(fabricator.IOStreams{}, g.flagparser).Short
+}
+
+// Option configures the fabricator command.
+type Option func(*config)
+
+type config struct {
+	generators map[string]Generator
+}
+
+// WithGenerators builds generators into the fabricator command. They are
+// keyed by the name of their plugin without prefix, e.g. "generate-go" for the
+// generator run by `fabricator generate go` instead of fabricator-generate-go,
+// and are looked up before the plugins on the plugin path.
+func WithGenerators(generators map[string]Generator) Option {
+	return func(c *config) {
+		if c.generators == nil {
+			c.generators = map[string]Generator{}
+		}
+		for name, generator := range generators {
+			c.generators[name] = generator
+		}
+	}
+}
+
+func newConfig(options []Option) *config {
+	c := &config{}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// generatorPathPrefix marks the paths generatorHandler looks up generators
+// at. It cannot be part of a file name on any platform.
+const generatorPathPrefix = "generator:"
+
+// generatorHandler is a plugin.PluginHandler which looks up generators before
+// the plugins of the wrapped handler.
+type generatorHandler struct {
+	plugin.PluginHandler
+	io         fabricator.IOStreams
+	generators map[string]Generator
+}
+
+// withGenerators returns handler looking up generators first.
+func withGenerators(handler plugin.PluginHandler, io fabricator.IOStreams, generators map[string]Generator) plugin.PluginHandler {
+	if len(generators) == 0 {
+		return handler
+	}
+	return &generatorHandler{PluginHandler: handler, io: io, generators: generators}
+}
+
+// Lookup implements PluginHandler
+func (h *generatorHandler) Lookup(ctx context.Context, filename string, paths []string) (string, bool) {
+	if _, ok := h.generators[filename]; ok {
+		return generatorPathPrefix + filename, true
+	}
+	return h.PluginHandler.Lookup(ctx, filename, paths)
+}
+
+// Execute implements PluginHandler
+func (h *generatorHandler) Execute(ctx context.Context, executablePath string, cmdArgs []string, environment fabricator.Environment) error {
+	if name, ok := strings.CutPrefix(executablePath, generatorPathPrefix); ok {
+		if generator, ok := h.generators[name]; ok {
+			return generator.Run(ctx, h.io, cmdArgs)
+		}
+	}
+	return h.PluginHandler.Execute(ctx, executablePath, cmdArgs, environment)
+}
+
+// addGeneratorCommands adds a command for every top-level command path
+// segment of generators to root, in the group plugin.CommandGroupID. The
+// commands run the generator, or a plugin if no generator matches the
+// arguments. Segments taken by a command of root are skipped.
+func addGeneratorCommands(root *cobra.Command, io fabricator.IOStreams, handler plugin.PluginHandler, flagparser fabricator.FlagParser, generators map[string]Generator) {
+	segments := map[string][]string{}
+	for name := range generators {
+		segment, _, _ := strings.Cut(name, "-")
+		segments[segment] = append(segments[segment], name)
+	}
+
+	for segment, names := range segments {
+		if cmd, _, err := root.Find([]string{segment}); err == nil && cmd != root {
+			io.Log().Warn("generator is shadowed by a command", "generator", segment, "command", cmd.CommandPath())
+			continue
+		}
+
+		sort.Strings(names)
+		short := fmt.Sprintf("built-in generators %s", strings.Join(names, ", "))
+		if generator, ok := generators[segment]; ok {
+			short = "built-in generator"
+			if d, ok := generator.(describer); ok && d.Description() != "" {
+				short = d.Description()
+			}
+		}
+
+		plugin.AddCommandGroup(root)
+		root.AddCommand(&cobra.Command{
+			Use:                segment,
+			Short:              short,
+			GroupID:            plugin.CommandGroupID,
+			DisableFlagParsing: true,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				wrapper := plugin.NewPluginWrapper(cmd.Context(), io, handler, flagparser, append([]string{segment}, args...))
+				return wrapper.RunE(wrapper, args)
+			},
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"code.cestus.io/tools/fabricator/pkg/fabricator"
+	"code.cestus.io/tools/fabricator/pkg/helpers"
+	"github.com/spf13/cobra"
+)
+
+func TestGenerators(t *testing.T) {
+	tests := []struct {
+		name             string
+		args             []string
+		generators       []string
+		expectGenerator  string
+		expectArgs       []string
+		expectPlugin     string
+		expectPluginArgs []string
+	}{
+		{
+			name:            "a generator is run instead of the plugin",
+			args:            []string{"fabricator", "foo", "--bar"},
+			generators:      []string{"foo"},
+			expectGenerator: "foo",
+			expectArgs:      []string{"--bar"},
+		},
+		{
+			name:            "a nested generator is run",
+			args:            []string{"fabricator", "gen", "api", "x"},
+			generators:      []string{"gen", "gen-api"},
+			expectGenerator: "gen-api",
+			expectArgs:      []string{"x"},
+		},
+		{
+			name:             "plugins are run without matching generator",
+			args:             []string{"fabricator", "foo", "--bar"},
+			generators:       []string{"foo-baz"},
+			expectPlugin:     "plugin/testdata/fabricator-foo",
+			expectPluginArgs: []string{"--bar"},
+		},
+		{
+			name:       "commands are not shadowed by generators",
+			args:       []string{"fabricator", "version"},
+			generators: []string{"version"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var ran string
+			var ranArgs []string
+			generators := map[string]Generator{}
+			for _, name := range test.generators {
+				name := name
+				generators[name] = GeneratorFunc(func(ctx context.Context, io fabricator.IOStreams, args []string) error {
+					ran, ranArgs = name, args
+					return nil
+				})
+			}
+			pluginsHandler := &testPluginHandler{pluginsDirectory: "plugin/testdata"}
+			io := fabricator.NewTestIOStreamsDiscard()
+
+			root := NewDefaultFabricatorCommandWithArgs(context.Background(), pluginsHandler, test.args, io, helpers.DefaultFlagParser, WithGenerators(generators))
+			root.SetArgs(test.args[1:])
+			root.SetOut(io.Out)
+			if err := root.Execute(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if ran != test.expectGenerator || strings.Join(ranArgs, " ") != strings.Join(test.expectArgs, " ") {
+				t.Errorf("want generator %q run with %q, have %q with %q", test.expectGenerator, test.expectArgs, ran, ranArgs)
+			}
+			if pluginsHandler.executedPlugin != test.expectPlugin || strings.Join(pluginsHandler.withArgs, " ") != strings.Join(test.expectPluginArgs, " ") {
+				t.Errorf("want plugin %q run with %q, have %q with %q", test.expectPlugin, test.expectPluginArgs, pluginsHandler.executedPlugin, pluginsHandler.withArgs)
+			}
+		})
+	}
+}
+
+func TestCommandGenerator(t *testing.T) {
+	t.Setenv("FABRICATOR_PLUGIN_PATH", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	newCommand := func(io fabricator.IOStreams, flagparser fabricator.FlagParser) *cobra.Command {
+		var greeting string
+		cmd := &cobra.Command{
+			Use:   "fabricator-hello",
+			Short: "Say hello",
+			// The flags are left to the flag parser, like in plugins reading
+			// them before cobra would.
+			DisableFlagParsing: true,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				if err := flagparser(cmd); err != nil {
+					return err
+				}
+				cmd.Printf("%s %s\n", greeting, strings.Join(cmd.Flags().Args(), " "))
+				return nil
+			},
+		}
+		cmd.Flags().StringVar(&greeting, "greeting", "hello", "the greeting")
+		return cmd
+	}
+	io, _, out, _ := fabricator.NewTestIOStreams()
+
+	root := NewFabricatorCommand(io, helpers.DefaultFlagParser, WithGenerators(map[string]Generator{
+		"hello": CommandGenerator(newCommand, helpers.DefaultFlagParser),
+	}))
+	root.SetArgs([]string{"hello", "--greeting", "hi", "world"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "hi world\n" {
+		t.Errorf("want the generator output, have %q", out)
+	}
+
+	var help bytes.Buffer
+	root.SetOut(&help)
+	root.SetArgs([]string{"--help"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(help.String(), "Plugin Commands:\n  hello       Say hello\n") {
+		t.Errorf("want the generator listed in the help, have %q", help.String())
+	}
+}
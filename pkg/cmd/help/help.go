@@ -2,8 +2,10 @@ package help
 
 import (
 	"errors"
+	"strings"
 
 	"code.cestus.io/tools/fabricator/internal/pkg/util"
+	"code.cestus.io/tools/fabricator/pkg/cmd/plugin"
 	"code.cestus.io/tools/fabricator/pkg/fabricator"
 	"github.com/spf13/cobra"
 )
@@ -20,24 +22,36 @@ func NewOptions(ioStreams fabricator.IOStreams) *options {
 	}
 }
 
-// Run executes help command of the root command
-func (o *options) Run() error {
-	if o.cmd.Root() == nil {
+// Run prints the help of the command named by args, or of the root command
+// if there are none. The help of a plugin is printed by running it with
+// --help.
+func (o *options) Run(args []string) error {
+	root := o.cmd.Root()
+	if root == nil {
 		return errors.New("not a subcommand")
 	}
-	return o.cmd.Root().Help()
+
+	cmd, rest, err := root.Find(args)
+	if err != nil || cmd == root && len(args) > 0 {
+		return util.UsageErrorf(o.cmd, "unknown help topic %q", strings.Join(args, " "))
+	}
+	if plugin.IsPluginCommand(cmd) {
+		return cmd.RunE(cmd, rest)
+	}
+	cmd.InitDefaultHelpFlag()
+	return cmd.Help()
 }
 
 // NewHelpCommand creaters a new help command
 func NewHelpCommand(ioStreams fabricator.IOStreams) *cobra.Command {
 	o := NewOptions(ioStreams)
 	cmd := &cobra.Command{
-		Use:     "help",
+		Use:     "help [command]",
 		Short:   "prints help",
-		Long:    "prints help",
+		Long:    "prints help about any command, or about a plugin by running it with --help",
 		Example: "",
-		Run: func(cmd *cobra.Command, args []string) {
-			util.CheckErr(o.Run())
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.Run(args)
 		},
 	}
 	o.cmd = cmd
@@ -0,0 +1,138 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"code.cestus.io/tools/fabricator/pkg/cmd/plugin"
+	"code.cestus.io/tools/fabricator/pkg/fabricator"
+)
+
+func TestHelp(t *testing.T) {
+	writePlugins(t, map[string]string{
+		"fabricator-foo":       "# fabricator-description: Generate the foo\necho \"foo $*\"",
+		"fabricator-foo-bar":   `echo "bar $*"`,
+		"fabricator-baz_qux":   `echo baz`,
+		"fabricator-version":   `echo version`,
+		"fabricator-notanexec": ``,
+	})
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	tests := []struct {
+		name      string
+		args      []string
+		expect    []string
+		notExpect []string
+	}{
+		{
+			name: "plugins are listed in the root help",
+			args: []string{"help"},
+			expect: []string{
+				"Available Commands:\n  completion",
+				"Plugin Commands:\n  baz-qux     plugin fabricator-baz_qux\n  foo         Generate the foo\n",
+			},
+			notExpect: []string{"plugin fabricator-version", "notanexec", "bar"},
+		},
+		{
+			name:   "plugins are listed in the root usage",
+			args:   []string{"--help"},
+			expect: []string{"Plugin Commands:\n  baz-qux"},
+		},
+		{
+			name:      "help of a command is printed",
+			args:      []string{"help", "version"},
+			expect:    []string{"Print the version", "fabricator version [flags]"},
+			notExpect: []string{"Available Commands:"},
+		},
+		{
+			name:   "help of a plugin is printed by the plugin",
+			args:   []string{"help", "foo", "x"},
+			expect: []string{"foo x --help\n"},
+		},
+		{
+			name:   "help of a nested plugin is printed by the plugin",
+			args:   []string{"help", "foo", "bar"},
+			expect: []string{"bar --help\n"},
+		},
+		{
+			name:   "--help is passed on to plugins",
+			args:   []string{"foo", "--help"},
+			expect: []string{"foo --help\n"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output, err := executeFabricator(t, test.args...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for _, s := range test.expect {
+				if !strings.Contains(output, s) {
+					t.Errorf("want %q in output, have %q", s, output)
+				}
+			}
+			for _, s := range test.notExpect {
+				if strings.Contains(output, s) {
+					t.Errorf("want no %q in output, have %q", s, output)
+				}
+			}
+		})
+	}
+}
+
+func TestHelpWithoutPlugins(t *testing.T) {
+	writePlugins(t, nil)
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	for _, args := range [][]string{{"help"}, {"--help"}} {
+		output, err := executeFabricator(t, args...)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.Contains(output, "Available Commands:") {
+			t.Errorf("want the commands listed for %q, have %q", args, output)
+		}
+		if strings.Contains(output, "Plugin Commands:") {
+			t.Errorf("want no plugin commands listed for %q, have %q", args, output)
+		}
+	}
+}
+
+func TestPluginAfterHelp(t *testing.T) {
+	writePlugins(t, map[string]string{
+		"fabricator-foo":     `echo "foo $*"`,
+		"fabricator-baz_qux": `echo baz`,
+	})
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	io, _, out, _ := fabricator.NewTestIOStreams()
+	args := []string{"fabricator", "foo", "x"}
+	handler := NewDefaultPluginHandler(plugin.ValidPluginFilenamePrefixes, io)
+	root := NewDefaultFabricatorCommandWithArgs(context.Background(), handler, args, io, envFlagParser)
+
+	var help bytes.Buffer
+	root.SetOut(&help)
+	root.SetArgs([]string{"--help"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(help.String(), "baz-qux") {
+		t.Fatalf("want baz-qux in the help, have %q", help.String())
+	}
+	if cmd, _, err := root.Find([]string{"baz-qux"}); err == nil && cmd != root {
+		t.Errorf("want no command for baz-qux after the help, have %q", cmd.CommandPath())
+	}
+
+	root.SetArgs(args[1:])
+	if err := root.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "foo x\n" {
+		t.Errorf("want the plugin output, have %q", out.String())
+	}
+}
@@ -0,0 +1,282 @@
+package initialize
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"code.cestus.io/tools/fabricator/internal/pkg/util"
+	"code.cestus.io/tools/fabricator/pkg/cmd/plugin"
+	"code.cestus.io/tools/fabricator/pkg/fabricator"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// APIVersion is the apiVersion of fab-files written by init.
+	APIVersion = "fabricator.cestus.io/v1alpha1"
+	// Kind is the kind of fab-files written by init.
+	Kind = "Config"
+)
+
+var initLong = `
+		Write a starter fab-file with one component for every --generator, given as
+		generator=component, e.g. --generator generate-go=api. The generators found on the
+		plugin path are listed in a comment.
+
+		With --interactive, components are asked for on the terminal instead.`
+
+var initExample = `
+		# Start a fab-file with the component api, generated by fabricator-generate-go
+		fabricator init --generator generate-go=api
+
+		# Choose components and generators interactively
+		fabricator init --interactive`
+
+// Component is a component of the fab-file written by init.
+type Component struct {
+	Name      string
+	Generator string
+}
+
+type options struct {
+	fabricator.RootOptions
+	fabricator.IOStreams
+	Generators  []string
+	Interactive bool
+	Force       bool
+
+	root *cobra.Command
+}
+
+// NewOptions returns initialized Options
+func NewOptions(ioStreams fabricator.IOStreams, flagset *pflag.FlagSet, flagparser fabricator.FlagParser) *options {
+	o := options{
+		IOStreams: ioStreams,
+	}
+	o.RootOptions.FlagParser = flagparser
+	o.RootOptions.RegisterOptions(flagset)
+	return &o
+}
+
+// Complete reads the flags of cmd
+func (o *options) Complete(cmd *cobra.Command) error {
+	if err := o.FlagParser(cmd); err != nil {
+		return err
+	}
+	o.root = cmd.Root()
+	return nil
+}
+
+// Validate checks the flags
+func (o *options) Validate(cmd *cobra.Command) error {
+	if o.Interactive && len(o.Generators) > 0 {
+		return util.UsageErrorf(cmd, "--generator cannot be used with --interactive")
+	}
+	_, err := parseGenerators(o.Generators)
+	if err != nil {
+		return util.UsageErrorf(cmd, "%v", err)
+	}
+	return nil
+}
+
+// Run writes the fab-file
+func (o *options) Run() error {
+	if !o.Force {
+		if _, err := os.Stat(o.FabricatorFile); err == nil {
+			return fmt.Errorf("%s already exists; use --force to overwrite it", o.FabricatorFile)
+		}
+	}
+
+	generators := o.availableGenerators()
+	components, err := parseGenerators(o.Generators)
+	if err != nil {
+		return err
+	}
+	if o.Interactive {
+		if components, err = o.ask(generators); err != nil {
+			return err
+		}
+	}
+
+	data, err := Render(components, generators)
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(o.FabricatorFile); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(o.FabricatorFile, data, 0o644); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(o.Out, "Wrote %s with %d components\n", o.FabricatorFile, len(components))
+	return nil
+}
+
+// availableGenerators returns the names of the executable plugins on the
+// plugin path which are not shadowed by a command, e.g. "fabricator-foo".
+func (o *options) availableGenerators() []string {
+	plugins, _ := plugin.DiscoverPlugins(plugin.SearchPaths(o.PluginPath))
+	verifier := plugin.NewCommandOverrideVerifier(o.root)
+
+	var generators []string
+	for _, p := range plugins {
+		if o.root != nil && len(verifier.Verify(p.Path)) > 0 {
+			// Not executable or shadowed.
+			continue
+		}
+		generators = append(generators, strings.TrimSuffix(p.Name, filepath.Ext(p.Name)))
+	}
+	return generators
+}
+
+// ask asks for components on In until an empty component name or the end of
+// input. Generators can be chosen by their number in generators.
+func (o *options) ask(generators []string) ([]Component, error) {
+	if o.In == nil {
+		return nil, errors.New("--interactive requires an input")
+	}
+
+	if len(generators) > 0 {
+		fmt.Fprintf(o.Out, "Generators found on the plugin path:\n")
+		for i, generator := range generators {
+			fmt.Fprintf(o.Out, "  %d) %s\n", i+1, generator)
+		}
+	}
+
+	scanner := bufio.NewScanner(o.In)
+	prompt := func(format string, args ...interface{}) (string, bool) {
+		fmt.Fprintf(o.Out, format, args...)
+		if !scanner.Scan() {
+			return "", false
+		}
+		return strings.TrimSpace(scanner.Text()), true
+	}
+
+	var components []Component
+	for {
+		name, ok := prompt("Component name (empty to finish): ")
+		if !ok || name == "" {
+			return components, scanner.Err()
+		}
+
+		for {
+			answer, ok := prompt("Generator for %s (number or name): ", name)
+			if !ok {
+				return components, scanner.Err()
+			}
+			if n, err := strconv.Atoi(answer); err == nil {
+				if n < 1 || n > len(generators) {
+					fmt.Fprintf(o.Out, "There is no generator %d.\n", n)
+					continue
+				}
+				answer = generators[n-1]
+			}
+			if answer == "" {
+				continue
+			}
+			components = append(components, Component{Name: name, Generator: generatorName(answer)})
+			break
+		}
+	}
+}
+
+// parseGenerators parses generator=component values.
+func parseGenerators(values []string) ([]Component, error) {
+	var components []Component
+	for _, value := range values {
+		generator, name, ok := strings.Cut(value, "=")
+		if !ok || generator == "" || name == "" {
+			return nil, fmt.Errorf("invalid --generator %q, must be generator=component", value)
+		}
+		components = append(components, Component{Name: name, Generator: generatorName(generator)})
+	}
+	return components, nil
+}
+
+// generatorName returns the plugin name of generator, e.g. "fabricator-foo"
+// for "foo".
+func generatorName(generator string) string {
+	for _, prefix := range plugin.ValidPluginFilenamePrefixes {
+		if strings.HasPrefix(generator, prefix+"-") {
+			return generator
+		}
+	}
+	return plugin.ValidPluginFilenamePrefixes[0] + "-" + generator
+}
+
+// Render returns a fab-file with components. The available generators are
+// listed in a comment.
+func Render(components []Component, generators []string) ([]byte, error) {
+	type component struct {
+		Name      string                 `yaml:"name"`
+		Generator string                 `yaml:"generator"`
+		Spec      map[string]interface{} `yaml:"spec"`
+	}
+	config := struct {
+		ApiVersion string      `yaml:"apiVersion"`
+		Kind       string      `yaml:"kind"`
+		Components []component `yaml:"components"`
+	}{
+		ApiVersion: APIVersion,
+		Kind:       Kind,
+		Components: []component{},
+	}
+	for _, c := range components {
+		config.Components = append(config.Components, component{Name: c.Name, Generator: c.Generator, Spec: map[string]interface{}{}})
+	}
+
+	var b bytes.Buffer
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(config); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	if len(generators) > 0 {
+		fmt.Fprintf(&b, "# Generators found on the plugin path:\n")
+		for _, generator := range generators {
+			fmt.Fprintf(&b, "#  - name: <component>\n#    generator: %s\n#    spec: {}\n", generator)
+		}
+	}
+	return b.Bytes(), nil
+}
+
+// NewCmdInit creates the init command
+func NewCmdInit(ioStreams fabricator.IOStreams, flagparser fabricator.FlagParser) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "init",
+		Short:   "Write a starter fab-file",
+		Long:    initLong,
+		Example: initExample,
+	}
+	o := NewOptions(ioStreams, cmd.Flags(), flagparser)
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if err := util.RequireNoArguments(cmd, args); err != nil {
+			return err
+		}
+		if err := o.Complete(cmd); err != nil {
+			return err
+		}
+		if err := o.Validate(cmd); err != nil {
+			return err
+		}
+		return o.Run()
+	}
+	cmd.Flags().StringArrayVar(&o.Generators, "generator", o.Generators, "Component to add, as generator=component; may be repeated")
+	cmd.Flags().BoolVarP(&o.Interactive, "interactive", "i", o.Interactive, "Ask for components on the terminal")
+	cmd.Flags().BoolVar(&o.Force, "force", o.Force, "Overwrite an existing fab-file")
+	return cmd
+}
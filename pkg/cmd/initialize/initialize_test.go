@@ -0,0 +1,172 @@
+package initialize
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"code.cestus.io/tools/fabricator/pkg/fabricator"
+	"code.cestus.io/tools/fabricator/pkg/helpers"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+func TestInit(t *testing.T) {
+	tests := []struct {
+		name             string
+		generators       []string
+		interactive      bool
+		input            string
+		existing         bool
+		force            bool
+		expectComponents []fabricator.FabricatorComponent
+		expectOut        string
+		expectErr        string
+	}{
+		{
+			name:       "components are written for generators",
+			generators: []string{"generate-go=api", "fabricator-foo=web"},
+			expectComponents: []fabricator.FabricatorComponent{
+				{Name: "api", Generator: "fabricator-generate-go"},
+				{Name: "web", Generator: "fabricator-foo"},
+			},
+			expectOut: "with 2 components",
+		},
+		{
+			name:      "a starter fab-file is written without generators",
+			expectOut: "with 0 components",
+		},
+		{
+			name:      "existing fab-files are kept",
+			existing:  true,
+			expectErr: "already exists; use --force to overwrite it",
+		},
+		{
+			name:             "existing fab-files are overwritten with force",
+			existing:         true,
+			force:            true,
+			generators:       []string{"foo=api"},
+			expectComponents: []fabricator.FabricatorComponent{{Name: "api", Generator: "fabricator-foo"}},
+		},
+		{
+			name:        "components are asked for interactively",
+			interactive: true,
+			input:       "api\n3\n1\nweb\ngenerate-go\n\n",
+			expectComponents: []fabricator.FabricatorComponent{
+				{Name: "api", Generator: "fabricator-foo"},
+				{Name: "web", Generator: "fabricator-generate-go"},
+			},
+			expectOut: "  1) fabricator-foo\nComponent name (empty to finish): Generator for api (number or name): There is no generator 3.\n",
+		},
+		{
+			name:             "interactive input may end without empty line",
+			interactive:      true,
+			input:            "api\nfoo",
+			expectComponents: []fabricator.FabricatorComponent{{Name: "api", Generator: "fabricator-foo"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			pluginDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(pluginDir, "fabricator-foo"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("PATH", "")
+			fabfile := filepath.Join(dir, ".fabricator.yml")
+			if test.existing {
+				if err := os.WriteFile(fabfile, []byte("existing"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			root := &cobra.Command{Use: "fabricator"}
+			root.AddCommand(&cobra.Command{Use: "init"})
+
+			io, in, out, _ := fabricator.NewTestIOStreams()
+			in.WriteString(test.input)
+			o := &options{
+				RootOptions: fabricator.RootOptions{FabricatorFile: fabfile, PluginPath: pluginDir},
+				IOStreams:   io,
+				Generators:  test.generators,
+				Interactive: test.interactive,
+				Force:       test.force,
+				root:        root,
+			}
+
+			err := o.Run()
+			if test.expectErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectErr) {
+					t.Fatalf("want error %q, have %v", test.expectErr, err)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !strings.Contains(out.String(), test.expectOut) {
+				t.Errorf("want %q in output, have %q", test.expectOut, out)
+			}
+
+			data, err := os.ReadFile(fabfile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var config fabricator.FabricatorConfig
+			if err := yaml.Unmarshal(data, &config); err != nil {
+				t.Fatalf("unexpected error reading fab-file: %v", err)
+			}
+			if config.ApiVersion != APIVersion || config.Kind != Kind {
+				t.Errorf("want apiVersion %s and kind %s, have %s and %s", APIVersion, Kind, config.ApiVersion, config.Kind)
+			}
+			if len(config.Components) != len(test.expectComponents) {
+				t.Fatalf("want components %+v, have %+v", test.expectComponents, config.Components)
+			}
+			for i, component := range config.Components {
+				if component.Name != test.expectComponents[i].Name || component.Generator != test.expectComponents[i].Generator {
+					t.Errorf("want component %+v, have %+v", test.expectComponents[i], component)
+				}
+			}
+			if !strings.Contains(string(data), "#    generator: fabricator-foo\n") {
+				t.Errorf("want generators found listed, have %q", data)
+			}
+		})
+	}
+}
+
+func TestInitFlags(t *testing.T) {
+	dir := t.TempDir()
+	pluginDir := t.TempDir()
+	t.Setenv("PATH", "")
+	fabfile := filepath.Join(dir, ".fabricator.yml")
+	args := []string{"init", "--generator", "foo=api", "--generator", "bar=web", "--fabfile", fabfile, "--plugin-path", pluginDir}
+
+	// The flag parser reads the commandline of the process as well.
+	osArgs := os.Args
+	os.Args = append([]string{"fabricator"}, args...)
+	defer func() { os.Args = osArgs }()
+
+	io, _, out, _ := fabricator.NewTestIOStreams()
+	root := &cobra.Command{Use: "fabricator"}
+	root.AddCommand(NewCmdInit(io, helpers.DefaultFlagParser))
+	root.SetArgs(args)
+	if err := root.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "with 2 components") {
+		t.Errorf("want 2 components written, have %q", out)
+	}
+
+	data, err := os.ReadFile(fabfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config fabricator.FabricatorConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		t.Fatalf("unexpected error reading fab-file: %v", err)
+	}
+	if len(config.Components) != 2 {
+		t.Errorf("want components api and web, have %+v", config.Components)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"syscall"
 
 	"code.cestus.io/tools/fabricator/pkg/fabricator"
 	"code.cestus.io/tools/fabricator/pkg/helpers"
@@ -74,9 +75,19 @@ func (h *DefaultPluginHandler) Lookup(ctx context.Context, filename string, path
 	return "", false
 }
 
-// Execute implements PluginHandler
+// Execute implements PluginHandler. A plugin which fails is reported as
+// fabricator.PluginError with its exit code.
 func (h *DefaultPluginHandler) Execute(ctx context.Context, executablePath string, cmdArgs []string, environment fabricator.Environment) error {
 	executor := helpers.NewExecutor("", h.IO).WithEnvMap(environment)
 	err := executor.Run(ctx, executablePath, cmdArgs...)
+
+	var execErr *helpers.ExecError
+	if errors.As(err, &execErr) {
+		code := execErr.ExitCode
+		if sig, ok := execErr.Signal.(syscall.Signal); ok && execErr.Signal != nil {
+			code = 128 + int(sig)
+		}
+		return &fabricator.PluginError{Path: executablePath, Code: code, Err: err}
+	}
 	return err
 }
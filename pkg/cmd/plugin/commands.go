@@ -0,0 +1,96 @@
+package plugin
+
+import (
+	"code.cestus.io/tools/fabricator/pkg/fabricator"
+	"github.com/spf13/cobra"
+)
+
+// CommandGroupID is the ID of the command group plugins are listed in.
+const CommandGroupID = "plugins"
+
+// commandAnnotation marks commands which run a plugin.
+const commandAnnotation = "fabricator.plugin"
+
+// IsPluginCommand reports whether cmd runs a plugin, i.e. was created by
+// NewPluginWrapper.
+func IsPluginCommand(cmd *cobra.Command) bool {
+	_, ok := cmd.Annotations[commandAnnotation]
+	return ok
+}
+
+// LookupPaths returns the directories plugins are looked up in, see
+// SearchPaths, for the plugin path read by flagparser. Flags not known to
+// fabricator are skipped.
+func LookupPaths(flagparser fabricator.FlagParser) []string {
+	cmd := &cobra.Command{}
+	o := NewOptions(fabricator.IOStreams{}, cmd.Flags(), flagparser)
+	cmd.Flags().ParseErrorsWhitelist.UnknownFlags = true
+	_ = o.FlagParser(cmd)
+	return SearchPaths(o.PluginPath)
+}
+
+// AddCommandGroup adds the group CommandGroupID to root, unless root has it
+// already. It is added with the first command in it, so that the help of root
+// lists no empty group.
+func AddCommandGroup(root *cobra.Command) {
+	if !root.ContainsGroup(CommandGroupID) {
+		root.AddGroup(&cobra.Group{ID: CommandGroupID, Title: "Plugin Commands:"})
+	}
+}
+
+// AddPluginCommands adds a command for every top-level command path segment
+// of the executable plugins in paths to root, in the group CommandGroupID,
+// so that they are listed in its help. Their short description is the
+// description of the plugin, cached in cacheFile (see DescribePlugins).
+// Plugins shadowed by a command of root are left out.
+//
+// The commands only describe plugins and do not run them; they are returned
+// to be removed with root.RemoveCommand once the help is rendered.
+func AddPluginCommands(root *cobra.Command, paths []string, cacheFile string) []*cobra.Command {
+	plugins, _ := DiscoverPlugins(paths)
+
+	var topLevel []Plugin
+	index := map[string]int{}
+	for _, p := range plugins {
+		if isExec, _ := isExecutable(p.Path); !isExec {
+			continue
+		}
+		name := p.CommandPath()[0]
+		if cmd, _, err := root.Find([]string{name}); err == nil && cmd != root {
+			continue
+		}
+		// The plugin providing the command itself describes it best, e.g.
+		// fabricator-foo rather than fabricator-foo-bar.
+		i, seen := index[name]
+		switch {
+		case !seen:
+			index[name] = len(topLevel)
+			topLevel = append(topLevel, p)
+		case len(p.CommandPath()) == 1 && len(topLevel[i].CommandPath()) > 1:
+			topLevel[i] = p
+		}
+	}
+
+	DescribePlugins(topLevel, cacheFile)
+
+	if len(topLevel) == 0 {
+		return nil
+	}
+	AddCommandGroup(root)
+	commands := make([]*cobra.Command, 0, len(topLevel))
+	for _, p := range topLevel {
+		short := p.Description
+		if short == "" {
+			short = "plugin " + p.Name
+		}
+		commands = append(commands, &cobra.Command{
+			Use:                p.CommandPath()[0],
+			Short:              short,
+			GroupID:            CommandGroupID,
+			DisableFlagParsing: true,
+			Run:                func(*cobra.Command, []string) {},
+		})
+	}
+	root.AddCommand(commands...)
+	return commands
+}
@@ -0,0 +1,144 @@
+package plugin
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.cestus.io/tools/fabricator/pkg/fabricator"
+	"code.cestus.io/tools/fabricator/pkg/helpers"
+	"github.com/spf13/cobra"
+)
+
+// CompletePluginNames returns the completions of toComplete with the next
+// command path segment of the executable plugins in paths below args, e.g.
+// "bar" for fabricator-foo-bar after "foo". Top-level plugins shadowed by a
+// command of root are left out.
+func CompletePluginNames(root *cobra.Command, paths []string, args []string, toComplete string) []string {
+	plugins, _ := DiscoverPlugins(paths)
+
+	var completions []string
+	seen := map[string]bool{}
+	for _, p := range plugins {
+		commandPath := p.CommandPath()
+		if len(commandPath) <= len(args) || !slices.Equal(commandPath[:len(args)], args) {
+			continue
+		}
+		segment := commandPath[len(args)]
+		if seen[segment] || !strings.HasPrefix(segment, toComplete) {
+			continue
+		}
+		if isExec, _ := isExecutable(p.Path); !isExec {
+			continue
+		}
+		if len(args) == 0 {
+			if cmd, _, err := root.Find([]string{segment}); err == nil && cmd != root {
+				continue
+			}
+		}
+		seen[segment] = true
+		completions = append(completions, segment+"\tplugin "+p.Name)
+	}
+	return completions
+}
+
+// completionTimeout is the time a completion executable may take to answer.
+const completionTimeout = 2 * time.Second
+
+// completePluginArgs completes the arguments of a plugin command. cmdArgs is
+// the command path of the plugin followed by its arguments. Plugin names are
+// completed while no flag was given; the arguments of the plugin found are
+// completed by its completion executable, see lookupCompleter.
+func completePluginArgs(ctx context.Context, handler PluginHandler, root *cobra.Command, paths []string, cmdArgs []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var names []string
+	for _, arg := range cmdArgs {
+		if strings.HasPrefix(arg, "-") {
+			break
+		}
+		names = append(names, arg)
+	}
+
+	var completions []string
+	directive := cobra.ShellCompDirectiveNoFileComp
+	if len(names) == len(cmdArgs) && !strings.HasPrefix(toComplete, "-") {
+		completions = CompletePluginNames(root, paths, names, toComplete)
+	}
+
+	underscored := make([]string, len(names))
+	for i, name := range names {
+		underscored[i] = strings.ReplaceAll(name, "-", "_")
+	}
+	path, n := findPlugin(ctx, handler, underscored, paths)
+	if path == "" {
+		return completions, directive
+	}
+
+	completer, found := lookupCompleter(path)
+	if !found {
+		// Let the shell complete file names.
+		return completions, cobra.ShellCompDirectiveDefault
+	}
+	pluginCompletions, pluginDirective, ok := completePlugin(ctx, completer, cmdArgs[n:], toComplete)
+	if !ok {
+		return completions, cobra.ShellCompDirectiveDefault
+	}
+	return append(completions, pluginCompletions...), pluginDirective
+}
+
+// lookupCompleter returns the executable which completes the arguments of
+// the plugin at path. Plugins opt in to completion with an executable next to
+// them named like them, with "_complete" appended to the prefix, e.g.
+// fabricator_complete-foo for fabricator-foo. It is run with the arguments to
+// complete and answers in cobra's __complete protocol; for a plugin built with
+// cobra, the script
+//
+//	#!/bin/sh
+//	exec fabricator-foo __complete "$@"
+//
+// does. Plugins without one are never run to complete their arguments.
+func lookupCompleter(path string) (string, bool) {
+	for _, prefix := range ValidPluginFilenamePrefixes {
+		name, ok := strings.CutPrefix(filepath.Base(path), prefix+"-")
+		if !ok {
+			continue
+		}
+		// LookPath also finds the executable by its extension on Windows.
+		if completer, err := exec.LookPath(filepath.Join(filepath.Dir(path), prefix+"_complete-"+name)); err == nil {
+			return completer, true
+		}
+	}
+	return "", false
+}
+
+// completePlugin asks the completion executable at path for the completions
+// of toComplete after args, using cobra's __complete protocol: one completion
+// per line, followed by a line with ":" and the directive. ok is false if it
+// does not answer in that protocol within completionTimeout.
+func completePlugin(ctx context.Context, path string, args []string, toComplete string) (completions []string, directive cobra.ShellCompDirective, ok bool) {
+	executor := helpers.NewExecutor("", fabricator.IOStreams{}).WithTimeout(completionTimeout).WithGracePeriod(0)
+	out, err := executor.Output(ctx, path, append(append([]string{}, args...), toComplete)...)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveDefault, false
+	}
+
+	lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+	last := lines[len(lines)-1]
+	if !strings.HasPrefix(last, ":") {
+		return nil, cobra.ShellCompDirectiveDefault, false
+	}
+	d, err := strconv.Atoi(last[1:])
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveDefault, false
+	}
+
+	for _, line := range lines[:len(lines)-1] {
+		if line != "" {
+			completions = append(completions, line)
+		}
+	}
+	return completions, cobra.ShellCompDirective(d), true
+}
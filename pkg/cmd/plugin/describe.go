@@ -0,0 +1,211 @@
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DescriptionMarker introduces the description of a plugin, which fabricator
+// shows in its help. It must start a line, optionally after a comment leader,
+// and be followed by a blank and the description up to the end of the line,
+// e.g. in the comment
+//
+//	# fabricator-description: Generate the foo
+//
+// of a script. Files are not executed to find it, so binaries declare it in
+// a string constant of the whole line, including both newlines, e.g.
+//
+//	const description = "\nfabricator-description: Generate the foo\n"
+//
+// which they must use, e.g. through ParseDescription, to keep it in the
+// binary. Other occurrences of the marker, like the one of this constant in
+// every binary linking this package, are ignored.
+const DescriptionMarker = "fabricator-description:"
+
+const maxDescriptionLength = 120
+
+// commentLeaders are the characters which may precede DescriptionMarker on
+// its line.
+const commentLeaders = " \t#/;*-"
+
+// ParseDescription returns the description of a DescriptionMarker line, e.g.
+// "Generate the foo" for "\nfabricator-description: Generate the foo\n".
+func ParseDescription(line string) string {
+	_, description, _ := strings.Cut(line, ":")
+	return strings.TrimSpace(description)
+}
+
+// ReadDescription returns the description of the plugin file at path, see
+// DescriptionMarker, or an empty string if it has none.
+func ReadDescription(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	// lineStart is true at the start of a line and while reading comment
+	// leaders there, the only places the marker is looked for.
+	lineStart := true
+	matched := 0
+	for {
+		b, err := r.ReadByte()
+		if err == io.EOF {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if lineStart && b == DescriptionMarker[matched] {
+			if matched++; matched < len(DescriptionMarker) {
+				continue
+			}
+			matched, lineStart = 0, false
+			if description, ok := readDescriptionLine(r); ok {
+				return description, nil
+			}
+			continue
+		}
+		matched = 0
+
+		switch {
+		case b == '\n':
+			lineStart = true
+		case lineStart && strings.IndexByte(commentLeaders, b) < 0:
+			lineStart = false
+		}
+	}
+}
+
+// readDescriptionLine reads the rest of the line from r, which must start
+// with a blank and end with a line break. ok is false unless it is a
+// non-empty line of printable text; r is then left at the first control
+// character, e.g. the line break.
+func readDescriptionLine(r *bufio.Reader) (string, bool) {
+	var line []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil || len(line) > 4*maxDescriptionLength {
+			return "", false
+		}
+		if b < ' ' && b != '\t' || b == 0x7f {
+			_ = r.UnreadByte()
+			if b != '\n' && b != '\r' {
+				return "", false
+			}
+			break
+		}
+		line = append(line, b)
+	}
+
+	if len(line) == 0 || line[0] != ' ' && line[0] != '\t' {
+		return "", false
+	}
+	description := strings.TrimSpace(string(line))
+	if description == "" || !utf8.ValidString(description) {
+		return "", false
+	}
+	for _, c := range description {
+		if !unicode.IsPrint(c) && c != '\t' {
+			return "", false
+		}
+	}
+	if runes := []rune(description); len(runes) > maxDescriptionLength {
+		description = string(runes[:maxDescriptionLength-3]) + "..."
+	}
+	return description, true
+}
+
+// DefaultDescriptionCacheFile returns the file plugin descriptions are cached
+// in, below the user's cache directory, or an empty string if there is none.
+func DefaultDescriptionCacheFile() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "fabricator", "plugin-descriptions.json")
+}
+
+type cachedDescription struct {
+	Size        int64     `json:"size"`
+	ModTime     time.Time `json:"modTime"`
+	Description string    `json:"description"`
+}
+
+// DescribePlugins sets the Description of plugins, read with ReadDescription.
+// Unless cacheFile is empty, descriptions are cached in it by path, size and
+// modification time of the plugin file, so that files are only read again
+// when they change. Plugins which cannot be read get no description.
+func DescribePlugins(plugins []Plugin, cacheFile string) {
+	cache := map[string]cachedDescription{}
+	if cacheFile != "" {
+		if data, err := os.ReadFile(cacheFile); err == nil {
+			// A corrupt cache is rebuilt.
+			_ = json.Unmarshal(data, &cache)
+		}
+	}
+
+	updated := make(map[string]cachedDescription, len(plugins))
+	changed := len(cache) != len(plugins)
+	for i, p := range plugins {
+		info, err := os.Stat(p.Path)
+		if err != nil {
+			continue
+		}
+
+		entry, ok := cache[p.Path]
+		if !ok || entry.Size != info.Size() || !entry.ModTime.Equal(info.ModTime()) {
+			description, err := ReadDescription(p.Path)
+			if err != nil {
+				continue
+			}
+			entry = cachedDescription{Size: info.Size(), ModTime: info.ModTime(), Description: description}
+			changed = true
+		}
+		updated[p.Path] = entry
+		plugins[i].Description = entry.Description
+	}
+
+	if cacheFile != "" && changed {
+		// Failing to cache only costs time.
+		_ = writeDescriptionCache(cacheFile, updated)
+	}
+}
+
+func writeDescriptionCache(cacheFile string, cache map[string]cachedDescription) error {
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(cacheFile), 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so that concurrent runs never read a
+	// partial cache.
+	tmp, err := os.CreateTemp(filepath.Dir(cacheFile), filepath.Base(cacheFile)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), cacheFile)
+	}
+	if err != nil {
+		return errors.Join(err, os.Remove(tmp.Name()))
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package plugin
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadDescription(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		expect  string
+	}{
+		{
+			name:    "script comment",
+			content: "#!/bin/sh\n# fabricator-description: Generate the foo \necho foo\n",
+			expect:  "Generate the foo",
+		},
+		{
+			name:    "go comment",
+			content: "package main\n\n\t// fabricator-description: Generate the foo\r\n",
+			expect:  "Generate the foo",
+		},
+		{
+			name:    "line in binary data",
+			content: "\x00\x01\nfabricator-description: Generate the foo\n\x00",
+			expect:  "Generate the foo",
+		},
+		{
+			name:    "marker within a line is skipped",
+			content: "\x00fabricator-description: garbage\n\x00echo fabricator-description: garbage\n# fabricator-description: Generate the foo\n",
+			expect:  "Generate the foo",
+		},
+		{
+			name:    "marker without blank is skipped",
+			content: "\nfabricator-description:garbage\n",
+		},
+		{
+			name:    "unterminated line is skipped",
+			content: "\nfabricator-description: garbage",
+		},
+		{
+			name:    "line with control characters is skipped",
+			content: "\nfabricator-description: garbage\x00\n",
+		},
+		{
+			name:    "no marker",
+			content: "#!/bin/sh\necho foo\n",
+		},
+		{
+			name:    "empty description",
+			content: "# fabricator-description:\n",
+		},
+		{
+			name:    "long description is truncated",
+			content: "# fabricator-description: " + strings.Repeat("a", 200) + "\n",
+			expect:  strings.Repeat("a", maxDescriptionLength-3) + "...",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "fabricator-foo")
+			if err := os.WriteFile(path, []byte(test.content), 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			description, err := ReadDescription(path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if description != test.expect {
+				t.Errorf("want description %q, have %q", test.expect, description)
+			}
+		})
+	}
+}
+
+func TestReadDescriptionOfGoBinaries(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds binaries")
+	}
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go is not installed")
+	}
+
+	tests := []struct {
+		name   string
+		source string
+		expect string
+	}{
+		{
+			name: "binary linking this package",
+			source: `package main
+
+import "code.cestus.io/tools/fabricator/pkg/cmd/plugin"
+
+func main() {
+	println(plugin.DescriptionMarker)
+}
+`,
+		},
+		{
+			name: "binary with description",
+			source: `package main
+
+import "code.cestus.io/tools/fabricator/pkg/cmd/plugin"
+
+const description = "\nfabricator-description: Generate the foo\n"
+
+func main() {
+	println(plugin.DescriptionMarker, plugin.ParseDescription(description))
+}
+`,
+			expect: "Generate the foo",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			source := filepath.Join(dir, "main.go")
+			if err := os.WriteFile(source, []byte(test.source), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(dir, "fabricator-foo")
+			// The source is built in the module of this package.
+			if out, err := exec.Command(gobin, "build", "-o", path, source).CombinedOutput(); err != nil {
+				t.Fatalf("building %s: %v\n%s", test.name, err, out)
+			}
+
+			description, err := ReadDescription(path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if description != test.expect {
+				t.Errorf("want description %q, have %q", test.expect, description)
+			}
+		})
+	}
+}
+
+func TestDescribePluginsCachesDescriptions(t *testing.T) {
+	dir := t.TempDir()
+	cacheFile := filepath.Join(dir, "cache", "plugin-descriptions.json")
+	path := filepath.Join(dir, "fabricator-foo")
+	write := func(content string, modTime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	describe := func() string {
+		t.Helper()
+		plugins := []Plugin{{Name: "fabricator-foo", Path: path}}
+		DescribePlugins(plugins, cacheFile)
+		return plugins[0].Description
+	}
+
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	write("# fabricator-description: first\n", modTime)
+	if description := describe(); description != "first" {
+		t.Fatalf("want description %q, have %q", "first", description)
+	}
+	if _, err := os.Stat(cacheFile); err != nil {
+		t.Fatalf("want cache written: %v", err)
+	}
+
+	// Same size and modification time; the cached description is used.
+	write("# fabricator-description: other\n", modTime)
+	if description := describe(); description != "first" {
+		t.Errorf("want cached description %q, have %q", "first", description)
+	}
+
+	write("# fabricator-description: other\n", modTime.Add(time.Minute))
+	if description := describe(); description != "other" {
+		t.Errorf("want changed description %q, have %q", "other", description)
+	}
+}
@@ -0,0 +1,93 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"code.cestus.io/libs/buildinfo"
+)
+
+// Plugin is a plugin file found on the plugin search paths.
+type Plugin struct {
+	// Name is the file name, e.g. "fabricator-foo-bar".
+	Name string
+	// Path is the full path of the file.
+	Path string
+	// Description is the description of the plugin, if set by
+	// DescribePlugins.
+	Description string
+}
+
+// CommandPath returns the fabricator command path the plugin provides, e.g.
+// ["foo", "bar"] for fabricator-foo-bar. A suffix with the OS and platform
+// fabricator was built for is dropped, and underscores become dashes.
+func (p Plugin) CommandPath() []string {
+	name := p.Name
+	if runtime.GOOS == "windows" {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	for _, prefix := range ValidPluginFilenamePrefixes {
+		if trimmed, ok := strings.CutPrefix(name, prefix+"-"); ok {
+			name = trimmed
+			break
+		}
+	}
+	info := buildinfo.ProvideBuildInfo()
+	name = strings.TrimSuffix(name, fmt.Sprintf("-%s-%s", info.OS, info.Platform))
+
+	segments := strings.Split(name, "-")
+	for i, segment := range segments {
+		segments[i] = strings.ReplaceAll(segment, "_", "-")
+	}
+	return segments
+}
+
+// SearchPaths returns the directories plugins are looked up in: those of
+// pluginPath, then those of $PATH.
+func SearchPaths(pluginPath string) []string {
+	return append(filepath.SplitList(pluginPath), filepath.SplitList(os.Getenv("PATH"))...)
+}
+
+// DiscoverPlugins returns the files with a valid plugin prefix in paths, in
+// lookup order, together with errors for directories which cannot be read.
+func DiscoverPlugins(paths []string) ([]Plugin, []error) {
+	var plugins []Plugin
+	var errs []error
+
+	for _, dir := range uniquePathsList(paths) {
+		if len(strings.TrimSpace(dir)) == 0 {
+			continue
+		}
+
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, f := range files {
+			if f.IsDir() {
+				continue
+			}
+			if !hasValidPrefix(f.Name(), ValidPluginFilenamePrefixes) {
+				continue
+			}
+			if runtime.GOOS != "windows" {
+				// filter out windows executables
+				fileExt := strings.ToLower(filepath.Ext(f.Name()))
+
+				switch fileExt {
+				case ".bat", ".cmd", ".com", ".exe", ".ps1":
+					continue
+				}
+			}
+
+			plugins = append(plugins, Plugin{Name: f.Name(), Path: filepath.Join(dir, f.Name())})
+		}
+	}
+
+	return plugins, errs
+}
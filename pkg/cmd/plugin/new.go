@@ -0,0 +1,279 @@
+package plugin
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"text/template"
+
+	"code.cestus.io/tools/fabricator/internal/pkg/util"
+	"code.cestus.io/tools/fabricator/pkg/fabricator"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/mod/module"
+)
+
+var (
+	pluginNewLong = `
+		Create the project of a new plugin named fabricator-<name> in the root directory.
+
+		With --lang go (the default), the project is a Go module with a main package wired
+		like fabricator itself, a ginkgo test suite and a Makefile; run "make build" to build
+		it. With --lang bash, it is a single executable script.`
+
+	pluginNewExample = `
+		# Create the Go project fabricator-generate-api
+		fabricator plugin new generate-api
+
+		# Create the script fabricator-hello
+		fabricator plugin new hello --lang bash`
+
+	// Languages are the languages plugin new creates plugins in.
+	Languages = []string{"go", "bash"}
+
+	//go:embed templates
+	templates embed.FS
+
+	validName = regexp.MustCompile(`^[a-z0-9]+([-_][a-z0-9]+)*$`)
+)
+
+// scaffoldFile is a file of a new plugin, rendered from a template.
+type scaffoldFile struct {
+	template string
+	path     string
+	mode     os.FileMode
+}
+
+// scaffoldFiles are the files of new plugins by language. Paths are relative to
+// the project directory; "{{.Binary}}" is replaced by the plugin name.
+var scaffoldFiles = map[string][]scaffoldFile{
+	"go": {
+		{"go/go.mod.tmpl", "go.mod", 0o644},
+		{"go/gitignore.tmpl", ".gitignore", 0o644},
+		{"go/Makefile.tmpl", "Makefile", 0o644},
+		{"go/main.go.tmpl", "cmd/{{.Binary}}/main.go", 0o644},
+		{"go/cmd.go.tmpl", "pkg/cmd/cmd.go", 0o644},
+		{"go/cmd_suite_test.go.tmpl", "pkg/cmd/cmd_suite_test.go", 0o644},
+		{"go/cmd_test.go.tmpl", "pkg/cmd/cmd_test.go", 0o644},
+	},
+	"bash": {
+		{"bash/plugin.sh.tmpl", "{{.Binary}}", 0o755},
+	},
+}
+
+// fabricatorModule is the module path of fabricator.
+const fabricatorModule = "code.cestus.io/tools/fabricator"
+
+// defaultRequires are the modules the go.mod of Go plugins requires besides
+// fabricator, with the versions of the go.mod of fabricator.
+var defaultRequires = []module.Version{
+	{Path: "github.com/onsi/ginkgo/v2", Version: "v2.22.2"},
+	{Path: "github.com/onsi/gomega", Version: "v1.36.2"},
+	{Path: "github.com/spf13/cobra", Version: "v1.8.1"},
+	{Path: "github.com/spf13/pflag", Version: "v1.0.6"},
+	{Path: "gopkg.in/yaml.v3", Version: "v3.0.1"},
+}
+
+// Requires returns the modules the go.mod of Go plugins requires, with the
+// versions in build, the build info of the running binary, where it has them,
+// or else those of the go.mod of fabricator. fabricator itself is left out if
+// its version is unknown, e.g. in binaries of go run; "make build" adds it
+// with go mod tidy then.
+func Requires(build *debug.BuildInfo) []module.Version {
+	versions := map[string]string{}
+	if build != nil {
+		for _, m := range append([]*debug.Module{&build.Main}, build.Deps...) {
+			// Builds from modified working trees are marked +dirty.
+			version := strings.TrimSuffix(m.Version, "+dirty")
+			if module.Check(m.Path, version) == nil {
+				versions[m.Path] = version
+			}
+		}
+	}
+
+	var requires []module.Version
+	if version, ok := versions[fabricatorModule]; ok {
+		requires = append(requires, module.Version{Path: fabricatorModule, Version: version})
+	}
+	for _, m := range defaultRequires {
+		if version, ok := versions[m.Path]; ok {
+			m.Version = version
+		}
+		requires = append(requires, m)
+	}
+	return requires
+}
+
+// Scaffold describes a new plugin.
+type Scaffold struct {
+	// Name is the plugin name without prefix, e.g. "generate-api".
+	Name string
+	// Binary is the file name of the plugin, e.g. "fabricator-generate-api".
+	Binary string
+	// Module is the Go module path of Go plugins.
+	Module string
+	// GoVersion is the go version of the go.mod of Go plugins.
+	GoVersion string
+	// Requires are the modules the go.mod of Go plugins requires, see
+	// Requires.
+	Requires []module.Version
+}
+
+type newOptions struct {
+	fabricator.RootOptions
+	fabricator.IOStreams
+	Lang   string
+	Module string
+	Force  bool
+}
+
+// NewCmdPluginNew creates the command to create the project of a new plugin
+func NewCmdPluginNew(streams fabricator.IOStreams, flagparser fabricator.FlagParser) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "new <name>",
+		Short:   "Create the project of a new plugin",
+		Long:    pluginNewLong,
+		Example: pluginNewExample,
+		Args:    cobra.ExactArgs(1),
+	}
+	o := newNewOptions(streams, cmd.Flags(), flagparser)
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if err := o.Complete(cmd); err != nil {
+			return err
+		}
+		if err := o.Validate(cmd, args[0]); err != nil {
+			return err
+		}
+		return o.Run(args[0])
+	}
+	cmd.Flags().StringVar(&o.Lang, "lang", "go", "Language of the plugin, one of go or bash")
+	cmd.Flags().StringVar(&o.Module, "module", "", "Go module path of the plugin; fabricator-<name> if not set")
+	cmd.Flags().BoolVar(&o.Force, "force", o.Force, "Write into an existing directory, overwriting its files")
+	_ = cmd.RegisterFlagCompletionFunc("lang", cobra.FixedCompletions(Languages, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+func newNewOptions(ioStreams fabricator.IOStreams, flagset *pflag.FlagSet, flagparser fabricator.FlagParser) *newOptions {
+	o := newOptions{
+		IOStreams: ioStreams,
+	}
+	o.RootOptions.FlagParser = flagparser
+	o.RootOptions.RegisterOptions(flagset)
+	return &o
+}
+
+// Complete reads the flags of cmd
+func (o *newOptions) Complete(cmd *cobra.Command) error {
+	return o.FlagParser(cmd)
+}
+
+// Validate checks the flags and the plugin name
+func (o *newOptions) Validate(cmd *cobra.Command, name string) error {
+	if _, ok := scaffoldFiles[o.Lang]; !ok {
+		return util.UsageErrorf(cmd, "unsupported language %q, must be go or bash", o.Lang)
+	}
+	if o.Module != "" && o.Lang != "go" {
+		return util.UsageErrorf(cmd, "--module can only be used with --lang go")
+	}
+	if !validName.MatchString(strings.TrimPrefix(name, ValidPluginFilenamePrefixes[0]+"-")) {
+		return util.UsageErrorf(cmd, "invalid plugin name %q, must consist of lower case letters and digits separated by - or _", name)
+	}
+	return nil
+}
+
+// Run writes the project of the plugin name into the root directory
+func (o *newOptions) Run(name string) error {
+	name = strings.TrimPrefix(name, ValidPluginFilenamePrefixes[0]+"-")
+	build, _ := debug.ReadBuildInfo()
+	scaffold := Scaffold{
+		Name:      name,
+		Binary:    ValidPluginFilenamePrefixes[0] + "-" + name,
+		Module:    o.Module,
+		GoVersion: "1.23",
+		Requires:  Requires(build),
+	}
+
+	if scaffold.Module == "" {
+		scaffold.Module = scaffold.Binary
+	}
+
+	files, err := RenderScaffold(o.Lang, scaffold)
+	if err != nil {
+		return err
+	}
+
+	dir := o.RootDirectory
+	if o.Lang == "go" {
+		dir = filepath.Join(dir, scaffold.Binary)
+	}
+	for path := range files {
+		if _, err := os.Stat(filepath.Join(dir, path)); err == nil && !o.Force {
+			return fmt.Errorf("%s already exists; use --force to overwrite it", filepath.Join(dir, path))
+		} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	for _, file := range scaffoldFiles[o.Lang] {
+		path := renderPath(file.path, scaffold)
+		target := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, files[path], file.mode); err != nil {
+			return err
+		}
+		// WriteFile keeps the mode of existing files.
+		if err := os.Chmod(target, file.mode); err != nil {
+			return err
+		}
+	}
+
+	if o.Lang == "go" {
+		fmt.Fprintf(o.Out, "Created %s; run \"make build\" in it to build %s\n", dir, scaffold.Binary)
+	} else {
+		fmt.Fprintf(o.Out, "Created %s\n", filepath.Join(dir, scaffold.Binary))
+	}
+	return nil
+}
+
+// RenderScaffold returns the files of a new plugin in lang, one of Languages,
+// by their path relative to the project directory.
+func RenderScaffold(lang string, scaffold Scaffold) (map[string][]byte, error) {
+	files, ok := scaffoldFiles[lang]
+	if !ok {
+		return nil, fmt.Errorf("unsupported language %q", lang)
+	}
+
+	rendered := map[string][]byte{}
+	for _, file := range files {
+		tmpl, err := template.ParseFS(templates, "templates/"+file.template)
+		if err != nil {
+			return nil, err
+		}
+		var b bytes.Buffer
+		if err := tmpl.Execute(&b, scaffold); err != nil {
+			return nil, err
+		}
+		data := b.Bytes()
+		if strings.HasSuffix(file.path, ".go") {
+			// Imports of the module are sorted by its path.
+			if data, err = format.Source(data); err != nil {
+				return nil, fmt.Errorf("%s: %w", file.template, err)
+			}
+		}
+		rendered[renderPath(file.path, scaffold)] = data
+	}
+	return rendered, nil
+}
+
+func renderPath(path string, scaffold Scaffold) string {
+	return strings.ReplaceAll(path, "{{.Binary}}", scaffold.Binary)
+}
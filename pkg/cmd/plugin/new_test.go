@@ -0,0 +1,280 @@
+package plugin
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"testing"
+
+	"code.cestus.io/tools/fabricator/pkg/fabricator"
+	"golang.org/x/mod/modfile"
+)
+
+func TestPluginNew(t *testing.T) {
+	tests := []struct {
+		name        string
+		plugin      string
+		lang        string
+		module      string
+		existing    string
+		force       bool
+		expectFiles []string
+		expectIn    map[string]string
+		expectErr   string
+	}{
+		{
+			name:   "go project",
+			plugin: "generate-api",
+			lang:   "go",
+			expectFiles: []string{
+				"fabricator-generate-api/.gitignore",
+				"fabricator-generate-api/Makefile",
+				"fabricator-generate-api/cmd/fabricator-generate-api/main.go",
+				"fabricator-generate-api/go.mod",
+				"fabricator-generate-api/pkg/cmd/cmd.go",
+				"fabricator-generate-api/pkg/cmd/cmd_suite_test.go",
+				"fabricator-generate-api/pkg/cmd/cmd_test.go",
+			},
+			expectIn: map[string]string{
+				"fabricator-generate-api/go.mod":                              "module fabricator-generate-api\n",
+				"fabricator-generate-api/Makefile":                            "APPLICATIONS            := fabricator-generate-api\n",
+				"fabricator-generate-api/cmd/fabricator-generate-api/main.go": "\"fabricator-generate-api/pkg/cmd\"",
+				"fabricator-generate-api/pkg/cmd/cmd.go":                      "const Generator = \"fabricator-generate-api\"",
+			},
+		},
+		{
+			name:   "go project with module and prefixed name",
+			plugin: "fabricator-foo",
+			lang:   "go",
+			module: "example.com/foo",
+			expectIn: map[string]string{
+				"fabricator-foo/go.mod":                     "module example.com/foo\n",
+				"fabricator-foo/cmd/fabricator-foo/main.go": "\"example.com/foo/pkg/cmd\"",
+			},
+		},
+		{
+			name:        "bash script",
+			plugin:      "hello",
+			lang:        "bash",
+			expectFiles: []string{"fabricator-hello"},
+			expectIn:    map[string]string{"fabricator-hello": "# fabricator-description: Generate the components of the fab-file which use fabricator-hello\n"},
+		},
+		{
+			name:      "existing files are kept",
+			plugin:    "hello",
+			lang:      "bash",
+			existing:  "fabricator-hello",
+			expectErr: "already exists; use --force to overwrite it",
+		},
+		{
+			name:     "existing files are overwritten with force",
+			plugin:   "hello",
+			lang:     "bash",
+			existing: "fabricator-hello",
+			force:    true,
+			expectIn: map[string]string{"fabricator-hello": "#!/usr/bin/env bash\n"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if test.existing != "" {
+				if err := os.WriteFile(filepath.Join(dir, test.existing), []byte("existing"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			streams, _, _, _ := fabricator.NewTestIOStreams()
+			o := &newOptions{
+				RootOptions: fabricator.RootOptions{RootDirectory: dir},
+				IOStreams:   streams,
+				Lang:        test.lang,
+				Module:      test.module,
+				Force:       test.force,
+			}
+			err := o.Run(test.plugin)
+			if test.expectErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectErr) {
+					t.Fatalf("want error %q, have %v", test.expectErr, err)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if test.expectFiles != nil {
+				var files []string
+				_ = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+					if err == nil && !d.IsDir() {
+						rel, _ := filepath.Rel(dir, path)
+						files = append(files, filepath.ToSlash(rel))
+					}
+					return err
+				})
+				sort.Strings(files)
+				if strings.Join(files, "\n") != strings.Join(test.expectFiles, "\n") {
+					t.Errorf("want files %q, have %q", test.expectFiles, files)
+				}
+			}
+			for file, expect := range test.expectIn {
+				data, err := os.ReadFile(filepath.Join(dir, file))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(string(data), expect) {
+					t.Errorf("want %q in %s, have %q", expect, file, data)
+				}
+			}
+			if test.lang == "bash" && runtime.GOOS != "windows" {
+				path := filepath.Join(dir, "fabricator-"+test.plugin)
+				if isExec, err := isExecutable(path); err != nil || !isExec {
+					t.Errorf("want %s executable, have %v, %v", path, isExec, err)
+				}
+			}
+		})
+	}
+}
+
+func TestRequires(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "..", "..", "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gomod, err := modfile.ParseLax("go.mod", data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions := map[string]string{}
+	for _, r := range gomod.Require {
+		versions[r.Mod.Path] = r.Mod.Version
+	}
+	for _, m := range defaultRequires {
+		if versions[m.Path] != m.Version {
+			t.Errorf("want the version %s of %s in go.mod, have %s in defaultRequires", versions[m.Path], m.Path, m.Version)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		build  *debug.BuildInfo
+		expect []string
+	}{
+		{
+			name:   "without build info",
+			expect: []string{"github.com/onsi/ginkgo/v2@v2.22.2", "github.com/onsi/gomega@v1.36.2", "github.com/spf13/cobra@v1.8.1", "github.com/spf13/pflag@v1.0.6", "gopkg.in/yaml.v3@v3.0.1"},
+		},
+		{
+			name: "released fabricator",
+			build: &debug.BuildInfo{
+				Main: debug.Module{Path: fabricatorModule, Version: "v1.2.3"},
+				Deps: []*debug.Module{{Path: "github.com/spf13/cobra", Version: "v1.9.0"}},
+			},
+			expect: []string{fabricatorModule + "@v1.2.3", "github.com/onsi/ginkgo/v2@v2.22.2", "github.com/onsi/gomega@v1.36.2", "github.com/spf13/cobra@v1.9.0", "github.com/spf13/pflag@v1.0.6", "gopkg.in/yaml.v3@v3.0.1"},
+		},
+		{
+			name: "fabricator linked into another command from a modified tree",
+			build: &debug.BuildInfo{
+				Main: debug.Module{Path: "example.com/tool", Version: "(devel)"},
+				Deps: []*debug.Module{{Path: fabricatorModule, Version: "v1.2.4-0.20250101000000-0123456789ab+dirty"}},
+			},
+			expect: []string{fabricatorModule + "@v1.2.4-0.20250101000000-0123456789ab", "github.com/onsi/ginkgo/v2@v2.22.2", "github.com/onsi/gomega@v1.36.2", "github.com/spf13/cobra@v1.8.1", "github.com/spf13/pflag@v1.0.6", "gopkg.in/yaml.v3@v3.0.1"},
+		},
+		{
+			name:   "fabricator of unknown version",
+			build:  &debug.BuildInfo{Main: debug.Module{Path: fabricatorModule, Version: "(devel)"}},
+			expect: []string{"github.com/onsi/ginkgo/v2@v2.22.2", "github.com/onsi/gomega@v1.36.2", "github.com/spf13/cobra@v1.8.1", "github.com/spf13/pflag@v1.0.6", "gopkg.in/yaml.v3@v3.0.1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var requires []string
+			for _, m := range Requires(test.build) {
+				requires = append(requires, m.String())
+			}
+			if strings.Join(requires, " ") != strings.Join(test.expect, " ") {
+				t.Errorf("want requires %q, have %q", test.expect, requires)
+			}
+		})
+	}
+}
+
+func TestPluginNewBuilds(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the plugin")
+	}
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go is not installed")
+	}
+	root, err := filepath.Abs(filepath.Join("..", "..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	streams, _, _, _ := fabricator.NewTestIOStreams()
+	o := &newOptions{
+		RootOptions: fabricator.RootOptions{RootDirectory: dir},
+		IOStreams:   streams,
+		Lang:        "go",
+	}
+	if err := o.Run("hello"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	project := filepath.Join(dir, "fabricator-hello")
+
+	// The plugin is built with fabricator from this tree, and its own go.mod
+	// rather than a -modfile the tests may run with.
+	gomod := filepath.Join(project, "go.mod")
+	data, err := os.ReadFile(gomod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = append(data, "\nreplace "+fabricatorModule+" => "+filepath.ToSlash(root)+"\n"...)
+	if err := os.WriteFile(gomod, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var env []string
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, "GOFLAGS=") {
+			env = append(env, kv)
+		}
+	}
+	goCommand := func(args ...string) (string, error) {
+		cmd := exec.Command(gobin, args...)
+		cmd.Dir = project
+		cmd.Env = env
+		out, err := cmd.CombinedOutput()
+		return string(out), err
+	}
+
+	if out, err := goCommand("mod", "tidy"); err != nil {
+		for _, offline := range []string{"GOPROXY=off", "dial tcp", "no such host", "connection refused"} {
+			if strings.Contains(out, offline) {
+				t.Skipf("the modules of the plugin cannot be downloaded: %s", out)
+			}
+		}
+		t.Fatalf("go mod tidy: %v\n%s", err, out)
+	}
+	binary := filepath.Join(project, "bin", "fabricator-hello")
+	if out, err := goCommand("build", "-o", binary, "./cmd/fabricator-hello"); err != nil {
+		t.Fatalf("go build: %v\n%s", err, out)
+	}
+	if out, err := goCommand("vet", "./..."); err != nil {
+		t.Fatalf("go vet: %v\n%s", err, out)
+	}
+
+	description, err := ReadDescription(binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := "Generate the components of the fab-file which use fabricator-hello"; description != expect {
+		t.Errorf("want description %q, have %q", expect, description)
+	}
+}
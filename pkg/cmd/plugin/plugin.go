@@ -28,7 +28,10 @@ var (
 		Available plugin files are those that are:
 		- executable
 		- anywhere on the user's PATH (or in the )
-		- begin with "fabricator-"`
+		- begin with "fabricator-"
+
+		Warnings about plugins which are not executable or shadowed make the command fail,
+		unless the list is printed with --output json or yaml.`
 
 	ValidPluginFilenamePrefixes = []string{"fabricator"}
 )
@@ -54,12 +57,13 @@ func NewCmdPlugin(streams fabricator.IOStreams, flagparser fabricator.FlagParser
 		DisableFlagsInUseLine: true,
 		Short:                 "Provides utilities for interacting with plugins.",
 		Long:                  pluginLong,
-		Run: func(cmd *cobra.Command, args []string) {
-			util.DefaultSubCommandRun(streams.ErrOut)(cmd, args)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return util.DefaultSubCommandRun(streams.ErrOut)(cmd, args)
 		},
 	}
 
 	cmd.AddCommand(NewCmdPluginList(streams, flagparser))
+	cmd.AddCommand(NewCmdPluginNew(streams, flagparser))
 	return cmd
 }
 
@@ -68,8 +72,11 @@ type Options struct {
 	fabricator.IOStreams
 	Verifier PathVerifier
 	NameOnly bool
+	Output   string
 
 	PluginPaths []string
+
+	root *cobra.Command
 }
 
 // NewOptions returns initialized Options
@@ -91,11 +98,17 @@ func NewCmdPluginList(streams fabricator.IOStreams, flagparser fabricator.FlagPa
 		Long:  pluginListLong,
 	}
 	o := NewOptions(streams, cmd.Flags(), flagparser)
-	cmd.Run = func(cmd *cobra.Command, args []string) {
-		util.CheckErr(o.Complete(cmd))
-		util.CheckErr(o.Run())
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if err := o.Complete(cmd); err != nil {
+			return err
+		}
+		if err := util.ValidateOutputFormat(cmd, o.Output); err != nil {
+			return err
+		}
+		return o.Run()
 	}
 	cmd.Flags().BoolVar(&o.NameOnly, "name-only", o.NameOnly, "If true, display only the binary name of each plugin, rather than its full path")
+	util.AddOutputFlag(cmd, &o.Output)
 	return cmd
 }
 
@@ -104,77 +117,98 @@ func (o *Options) Complete(cmd *cobra.Command) error {
 	if err != nil {
 		return err
 	}
-	o.Verifier = &CommandOverrideVerifier{
-		root:        cmd.Root(),
-		seenPlugins: make(map[string]string),
-	}
+	o.root = cmd.Root()
+	o.Verifier = NewCommandOverrideVerifier(cmd.Root())
 
-	o.PluginPaths = filepath.SplitList(o.PluginPath)
-	o.PluginPaths = append(o.PluginPaths, filepath.SplitList(os.Getenv("PATH"))...)
+	o.PluginPaths = SearchPaths(o.PluginPath)
 	return nil
 }
 
-func (o *Options) Run() error {
-	pluginsFound := false
-	isFirstFile := true
-	pluginErrors := []error{}
-	pluginWarnings := 0
+// PluginInfo describes a plugin file, as listed by the plugin list command
+// with --output. The field names are stable.
+type PluginInfo struct {
+	// Name is the file name, e.g. "fabricator-foo".
+	Name string `json:"name" yaml:"name"`
+	// Path is the full path of the file.
+	Path string `json:"path" yaml:"path"`
+	// Executable is false for files which cannot be run as plugin.
+	Executable bool `json:"executable" yaml:"executable"`
+	// ShadowedBy is the path of the plugin, or the command path of the
+	// fabricator command, which takes precedence over this plugin, if any.
+	ShadowedBy string `json:"shadowedBy,omitempty" yaml:"shadowedBy,omitempty"`
+	// Warnings are the problems found with the plugin.
+	Warnings []string `json:"warnings" yaml:"warnings"`
+}
 
-	for _, dir := range uniquePathsList(o.PluginPaths) {
-		if len(strings.TrimSpace(dir)) == 0 {
-			continue
+// PluginList is the output of the plugin list command with --output.
+type PluginList struct {
+	Plugins []PluginInfo `json:"plugins" yaml:"plugins"`
+}
+
+// List returns the plugins found on the plugin paths, in lookup order.
+// Directories which cannot be read are reported on ErrOut and skipped.
+func (o *Options) List() []PluginInfo {
+	plugins, errs := DiscoverPlugins(o.PluginPaths)
+	for _, err := range errs {
+		dir := ""
+		var pathErr *os.PathError
+		if errors.As(err, &pathErr) {
+			dir, err = pathErr.Path, pathErr.Err
 		}
+		fmt.Fprintf(o.ErrOut, "Unable to read directory %q from your PATH: %v. Skipping...\n", dir, err)
+	}
 
-		files, err := os.ReadDir(dir)
-		if err != nil {
-			if _, ok := err.(*os.PathError); ok {
-				fmt.Fprintf(o.ErrOut, "Unable to read directory %q from your PATH: %v. Skipping...\n", dir, err)
-				continue
+	infos := []PluginInfo{}
+	seen := map[string]string{}
+	for _, p := range plugins {
+		info := PluginInfo{Name: p.Name, Path: p.Path, Warnings: []string{}}
+		info.Executable, _ = isExecutable(p.Path)
+		if first, ok := seen[p.Name]; ok {
+			info.ShadowedBy = first
+		} else {
+			seen[p.Name] = p.Path
+		}
+		if o.root != nil && info.ShadowedBy == "" {
+			// the first segment is always the prefix of the plugin binary
+			if cmd, _, err := o.root.Find(strings.Split(p.Name, "-")[1:]); err == nil {
+				info.ShadowedBy = cmd.CommandPath()
 			}
-
-			pluginErrors = append(pluginErrors, fmt.Errorf("error: unable to read directory %q in your PATH: %v", dir, err))
-			continue
 		}
+		for _, err := range o.Verifier.Verify(p.Path) {
+			info.Warnings = append(info.Warnings, err.Error())
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
 
-		for _, f := range files {
-			if f.IsDir() {
-				continue
-			}
-			if !hasValidPrefix(f.Name(), ValidPluginFilenamePrefixes) {
-				continue
-			}
-			if runtime.GOOS != "windows" {
-				// filter out windows executables
-				fileExt := strings.ToLower(filepath.Ext(f.Name()))
-
-				switch fileExt {
-				case ".bat", ".cmd", ".com", ".exe", ".ps1":
-					continue
-				}
-			}
+func (o *Options) Run() error {
+	plugins := o.List()
+	if o.Output != "" {
+		// Problems are part of the output; only failing to write it fails.
+		return util.PrintObject(o.Out, o.Output, PluginList{Plugins: plugins})
+	}
 
-			if isFirstFile {
-				fmt.Fprintf(o.Out, "The following compatible plugins are available:\n\n")
-				pluginsFound = true
-				isFirstFile = false
-			}
+	var pluginErrors []error
+	pluginWarnings := 0
+	for i, p := range plugins {
+		if i == 0 {
+			fmt.Fprintf(o.Out, "The following compatible plugins are available:\n\n")
+		}
 
-			pluginPath := f.Name()
-			if !o.NameOnly {
-				pluginPath = filepath.Join(dir, pluginPath)
-			}
+		pluginPath := p.Path
+		if o.NameOnly {
+			pluginPath = p.Name
+		}
 
-			fmt.Fprintf(o.Out, "%s\n", pluginPath)
-			if errs := o.Verifier.Verify(filepath.Join(dir, f.Name())); len(errs) != 0 {
-				for _, err := range errs {
-					fmt.Fprintf(o.ErrOut, "  - %s\n", err)
-					pluginWarnings++
-				}
-			}
+		fmt.Fprintf(o.Out, "%s\n", pluginPath)
+		for _, warning := range p.Warnings {
+			fmt.Fprintf(o.ErrOut, "  - %s\n", warning)
+			pluginWarnings++
 		}
 	}
 
-	if !pluginsFound {
+	if len(plugins) == 0 {
 		pluginErrors = append(pluginErrors, fmt.Errorf("error: unable to find any fabricator plugins in your PATH"))
 	}
 
@@ -207,6 +241,15 @@ type CommandOverrideVerifier struct {
 	seenPlugins map[string]string
 }
 
+// NewCommandOverrideVerifier returns a CommandOverrideVerifier for the
+// commands of root.
+func NewCommandOverrideVerifier(root *cobra.Command) *CommandOverrideVerifier {
+	return &CommandOverrideVerifier{
+		root:        root,
+		seenPlugins: make(map[string]string),
+	}
+}
+
 // Verify implements PathVerifier and determines if a given path
 // is valid depending on whether or not it overwrites an existing
 // fabricator command path, or a previously seen plugin.
@@ -304,22 +347,33 @@ func NewPluginWrapper(ctx context.Context, streams fabricator.IOStreams, handler
 	// This cannot use the standard way of loading options, because we are trying to execute a plugin. But we will need to read the standard options to see if we have to use a plugin path.
 	o := NewOptions(streams, cmd.Flags(), flagparser)
 	// Most of the time FlagParser will complain about additional flags (since it cannot know what the plugin needs) so we ignore it here. The plugin is responsible to process the flags
+	// Unknown flags are skipped, so that the environment and config file are still read after them.
+	cmd.Flags().ParseErrorsWhitelist.UnknownFlags = true
+	// The arguments following the plugin name are the plugin's, and flags
+	// cannot be placed before it, so none of the commandline is read.
+	cmd.SetContext(fabricator.ContextWithArgs(ctx, nil))
 	o.FlagParser(cmd)
+	// --help is passed on to the plugin.
+	_ = cmd.Flags().Set("help", "false")
 
-	o.PluginPaths = filepath.SplitList(o.PluginPath)
-	o.PluginPaths = append(o.PluginPaths, filepath.SplitList(os.Getenv("PATH"))...)
-	fun, name, err := pluginCommandHandler(ctx, handler, pluginPathPieces, o.PluginPaths)
+	o.PluginPaths = SearchPaths(o.PluginPath)
+	// The logging of fabricator is passed on to the plugin.
+	fun, name, err := pluginCommandHandler(ctx, handler, pluginPathPieces, o.PluginPaths, fabricator.LogEnvironment(o.Verbosity, o.LogFormat))
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return err
 		}
 		return fun()
 	}
+	cmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return completePluginArgs(ctx, handler, cmd.Root(), o.PluginPaths, append([]string{cmd.Name()}, args...), toComplete)
+	}
 	cmd.Use = name
+	cmd.Annotations = map[string]string{commandAnnotation: name}
 	return cmd
 }
 
-func pluginCommandHandler(ctx context.Context, pluginHandler PluginHandler, cmdArgs []string, paths []string) (func() error, string, error) {
+func pluginCommandHandler(ctx context.Context, pluginHandler PluginHandler, cmdArgs []string, paths []string, environment fabricator.Environment) (func() error, string, error) {
 	var remainingArgs []string // all "non-flag" arguments
 	name := ""
 	if len(cmdArgs) > 0 {
@@ -334,38 +388,21 @@ func pluginCommandHandler(ctx context.Context, pluginHandler PluginHandler, cmdA
 
 	if len(remainingArgs) == 0 {
 		// the length of cmdArgs is at least 1
-		err := fmt.Errorf("flags cannot be placed before plugin name: %s", cmdArgs[0])
+		err := &fabricator.UsageError{Err: fmt.Errorf("flags cannot be placed before plugin name: %s", cmdArgs[0])}
 		return func() error { return err }, name, err
 	}
 
-	foundBinaryPath := ""
-
-	// attempt to find binary, starting at longest possible name with given cmdArgs
-	for len(remainingArgs) > 0 {
-		tentativeName := strings.Join(remainingArgs, "-")
-		// try extended name with GOOS-ARCH first so developpers can work with their locally build plugins
-		path, found := pluginHandler.Lookup(ctx, strings.Join([]string{tentativeName, buildinfo.ProvideBuildInfo().OS, buildinfo.ProvideBuildInfo().Platform}, "-"), paths)
-
-		if !found {
-			path, found = pluginHandler.Lookup(ctx, tentativeName, paths)
-		}
-		if !found {
-			remainingArgs = remainingArgs[:len(remainingArgs)-1]
-			continue
-		}
-
-		foundBinaryPath = path
-		break
-	}
+	foundBinaryPath, n := findPlugin(ctx, pluginHandler, remainingArgs, paths)
+	remainingArgs = remainingArgs[:n]
 
 	if len(foundBinaryPath) == 0 {
-		err := errors.New("no plugin found")
+		err := &fabricator.PluginNotFoundError{Name: name}
 		return func() error { return err }, name, err
 	}
 
 	exec := func() error {
 		// invoke cmd binary relaying the current environment and args given
-		if err := pluginHandler.Execute(ctx, foundBinaryPath, cmdArgs[len(remainingArgs):], make(fabricator.Environment)); err != nil {
+		if err := pluginHandler.Execute(ctx, foundBinaryPath, cmdArgs[len(remainingArgs):], environment); err != nil {
 			return err
 		}
 
@@ -375,6 +412,29 @@ func pluginCommandHandler(ctx context.Context, pluginHandler PluginHandler, cmdA
 	return exec, name, nil
 }
 
+// findPlugin looks up the plugin for the longest prefix of names, see
+// LookupPlugin. It returns the path of the plugin and the length of the
+// prefix, or 0 if there is none.
+func findPlugin(ctx context.Context, pluginHandler PluginHandler, names []string, paths []string) (string, int) {
+	for n := len(names); n > 0; n-- {
+		if path, found := LookupPlugin(ctx, pluginHandler, strings.Join(names[:n], "-"), paths); found {
+			return path, n
+		}
+	}
+	return "", 0
+}
+
+// LookupPlugin looks up the plugin named name, e.g. "foo" for fabricator-foo,
+// trying the name extended with the OS and platform fabricator was built for
+// first, so developers can work with their locally built plugins.
+func LookupPlugin(ctx context.Context, pluginHandler PluginHandler, name string, paths []string) (string, bool) {
+	path, found := pluginHandler.Lookup(ctx, strings.Join([]string{name, buildinfo.ProvideBuildInfo().OS, buildinfo.ProvideBuildInfo().Platform}, "-"), paths)
+	if !found {
+		path, found = pluginHandler.Lookup(ctx, name, paths)
+	}
+	return path, found
+}
+
 // HandlePluginCommand receives a pluginHandler and command-line arguments and attempts to find
 // a plugin executable on the PATH that satisfies the given arguments.
 func HandlePluginCommand(ctx context.Context, pluginHandler PluginHandler, cmdArgs []string, paths []string) error {
@@ -388,7 +448,7 @@ func HandlePluginCommand(ctx context.Context, pluginHandler PluginHandler, cmdAr
 
 	if len(remainingArgs) == 0 {
 		// the length of cmdArgs is at least 1
-		return fmt.Errorf("flags cannot be placed before plugin name: %s", cmdArgs[0])
+		return &fabricator.UsageError{Err: fmt.Errorf("flags cannot be placed before plugin name: %s", cmdArgs[0])}
 	}
 
 	foundBinaryPath := ""
@@ -1,13 +1,18 @@
 package plugin
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 
 	"code.cestus.io/tools/fabricator/pkg/fabricator"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 func TestPluginPathsAreUnaltered(t *testing.T) {
@@ -162,6 +167,82 @@ func TestPluginPathsAreValid(t *testing.T) {
 	}
 }
 
+func TestPluginListOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin files are not executables on windows")
+	}
+	dir, dir2 := t.TempDir(), t.TempDir()
+	for _, plugin := range []struct {
+		dir, name string
+		perm      os.FileMode
+	}{
+		{dir, "fabricator-foo", 0o755},
+		{dir, "fabricator-bar", 0o644},
+		{dir2, "fabricator-foo", 0o755},
+		{dir2, "fabricator-version", 0o755},
+	} {
+		if err := os.WriteFile(filepath.Join(plugin.dir, plugin.name), []byte("#!/bin/sh\n"), plugin.perm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root := &cobra.Command{Use: "fabricator"}
+	root.AddCommand(&cobra.Command{Use: "version"})
+
+	expect := PluginList{Plugins: []PluginInfo{
+		{Name: "fabricator-bar", Path: filepath.Join(dir, "fabricator-bar"), Warnings: []string{"not executable"}},
+		{Name: "fabricator-foo", Path: filepath.Join(dir, "fabricator-foo"), Executable: true, Warnings: []string{}},
+		{Name: "fabricator-foo", Path: filepath.Join(dir2, "fabricator-foo"), Executable: true, ShadowedBy: filepath.Join(dir, "fabricator-foo"), Warnings: []string{"overshadowed"}},
+		{Name: "fabricator-version", Path: filepath.Join(dir2, "fabricator-version"), Executable: true, ShadowedBy: "fabricator version", Warnings: []string{"overwrites existing command"}},
+	}}
+	for _, format := range []string{"json", "yaml"} {
+		t.Run(format, func(t *testing.T) {
+			ioStreams, _, out, _ := fabricator.NewTestIOStreams()
+			o := &Options{
+				IOStreams:   ioStreams,
+				Output:      format,
+				PluginPaths: []string{dir, dir2},
+				root:        root,
+				Verifier:    &CommandOverrideVerifier{root: root, seenPlugins: make(map[string]string)},
+			}
+
+			// Warnings are part of the output.
+			if err := o.Run(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var list PluginList
+			var err error
+			if format == "json" {
+				err = json.Unmarshal(out.Bytes(), &list)
+			} else {
+				err = yaml.Unmarshal(out.Bytes(), &list)
+			}
+			if err != nil {
+				t.Fatalf("unexpected error decoding %q: %v", out, err)
+			}
+
+			if len(list.Plugins) != len(expect.Plugins) {
+				t.Fatalf("want plugins %+v, have %+v", expect.Plugins, list.Plugins)
+			}
+			for i, plugin := range list.Plugins {
+				want := expect.Plugins[i]
+				if plugin.Name != want.Name || plugin.Path != want.Path || plugin.Executable != want.Executable || plugin.ShadowedBy != want.ShadowedBy {
+					t.Errorf("want plugin %+v, have %+v", want, plugin)
+				}
+				if len(plugin.Warnings) != len(want.Warnings) {
+					t.Errorf("want warnings %q for %s, have %q", want.Warnings, want.Path, plugin.Warnings)
+					continue
+				}
+				for j, warning := range want.Warnings {
+					if !strings.Contains(plugin.Warnings[j], warning) {
+						t.Errorf("want warning %q for %s, have %q", warning, want.Path, plugin.Warnings[j])
+					}
+				}
+			}
+		})
+	}
+}
+
 type duplicatePathError struct {
 	path string
 }
@@ -9,8 +9,33 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// Info is the version of fabricator, as printed by the version command with
+// --output. The field names are stable.
+type Info struct {
+	Name      string `json:"name" yaml:"name"`
+	Version   string `json:"version" yaml:"version"`
+	BuildDate string `json:"buildDate" yaml:"buildDate"`
+	GoVersion string `json:"goVersion" yaml:"goVersion"`
+	Platform  string `json:"platform" yaml:"platform"`
+	OS        string `json:"os" yaml:"os"`
+}
+
+// NewInfo returns the Info of the running fabricator.
+func NewInfo() Info {
+	version := buildinfo.ProvideBuildInfo()
+	return Info{
+		Name:      version.Name,
+		Version:   version.Version,
+		BuildDate: version.BuildDate,
+		GoVersion: version.GoVersion,
+		Platform:  version.Platform,
+		OS:        version.OS,
+	}
+}
+
 type options struct {
 	fabricator.IOStreams
+	Output string
 }
 
 // NewOptions returns initialized Options
@@ -22,7 +47,11 @@ func NewOptions(ioStreams fabricator.IOStreams) *options {
 
 // Run executes version command
 func (o *options) Run() error {
-	version := buildinfo.ProvideBuildInfo()
+	version := NewInfo()
+	if o.Output != "" {
+		return util.PrintObject(o.Out, o.Output, version)
+	}
+
 	fmt.Fprintf(o.Out, "Name:       %s\n", version.Name)
 	fmt.Fprintf(o.Out, "Version:    %s\n", version.Version)
 	fmt.Fprintf(o.Out, "BuildDate:  %s\n", version.BuildDate)
@@ -38,10 +67,14 @@ func NewCmdVersion(ioStreams fabricator.IOStreams) *cobra.Command {
 		Use:     "version",
 		Short:   "Print the version",
 		Long:    "Print the version",
-		Example: "",
-		Run: func(cmd *cobra.Command, args []string) {
-			util.CheckErr(o.Run())
+		Example: "  fabricator version -o json",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := util.ValidateOutputFormat(cmd, o.Output); err != nil {
+				return err
+			}
+			return o.Run()
 		},
 	}
+	util.AddOutputFlag(cmd, &o.Output)
 	return cmd
 }
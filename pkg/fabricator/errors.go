@@ -0,0 +1,130 @@
+package fabricator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// Exit codes of fabricator. A failed plugin exits fabricator with its own exit
+// code instead, see PluginError.
+const (
+	// ExitOK means success.
+	ExitOK = 0
+	// ExitError is the exit code of errors without a more specific one.
+	ExitError = 1
+	// ExitUsage means the commandline is invalid, see UsageError.
+	ExitUsage = 2
+	// ExitConfig means the configuration is invalid, see ConfigError.
+	ExitConfig = 3
+	// ExitPluginNotFound means no plugin was found for the command, see
+	// PluginNotFoundError.
+	ExitPluginNotFound = 127
+	// ExitInterrupted means fabricator was interrupted by SIGINT, see
+	// InterruptedError.
+	ExitInterrupted = 130
+)
+
+// ExitCoder is implemented by errors which exit fabricator with a specific
+// exit code.
+type ExitCoder interface {
+	ExitCode() int
+}
+
+// ExitCode returns the exit code for err: ExitOK if it is nil, the exit code of
+// the first ExitCoder in its chain, or ExitError.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var coder ExitCoder
+	if errors.As(err, &coder) {
+		return coder.ExitCode()
+	}
+	return ExitError
+}
+
+// UsageError is an invalid commandline, e.g. an unknown flag or a missing
+// argument.
+type UsageError struct {
+	Err error
+}
+
+func (e *UsageError) Error() string { return e.Err.Error() }
+func (e *UsageError) Unwrap() error { return e.Err }
+
+// ExitCode returns ExitUsage.
+func (e *UsageError) ExitCode() int { return ExitUsage }
+
+// ConfigError is an invalid configuration, e.g. a config file or fab-file
+// which cannot be read, or an environment variable with an invalid value.
+type ConfigError struct {
+	// Path is the file which is invalid, if any.
+	Path string
+	Err  error
+}
+
+func (e *ConfigError) Error() string {
+	if e.Path != "" {
+		return fmt.Sprintf("%s: %v", e.Path, e.Err)
+	}
+	return e.Err.Error()
+}
+
+func (e *ConfigError) Unwrap() error { return e.Err }
+
+// ExitCode returns ExitConfig.
+func (e *ConfigError) ExitCode() int { return ExitConfig }
+
+// PluginNotFoundError means no plugin was found for a command.
+type PluginNotFoundError struct {
+	// Name is the command the plugin was looked up for, e.g. "foo".
+	Name string
+}
+
+func (e *PluginNotFoundError) Error() string {
+	return fmt.Sprintf("unknown command %q and no plugin found for it", e.Name)
+}
+
+// ExitCode returns ExitPluginNotFound.
+func (e *PluginNotFoundError) ExitCode() int { return ExitPluginNotFound }
+
+// PluginError means a plugin failed.
+type PluginError struct {
+	// Path is the plugin executable.
+	Path string
+	// Code is the exit code of the plugin, or, if it was terminated by a
+	// signal, 128 plus the signal number, like shells report it.
+	Code int
+	Err  error
+}
+
+func (e *PluginError) Error() string { return e.Err.Error() }
+func (e *PluginError) Unwrap() error { return e.Err }
+
+// ExitCode returns the exit code of the plugin, or ExitError if it is not
+// known.
+func (e *PluginError) ExitCode() int {
+	if e.Code <= 0 {
+		return ExitError
+	}
+	return e.Code
+}
+
+// InterruptedError means fabricator was stopped by a signal.
+type InterruptedError struct {
+	Signal os.Signal
+}
+
+func (e *InterruptedError) Error() string {
+	return fmt.Sprintf("interrupted by %s", e.Signal)
+}
+
+// ExitCode returns 128 plus the signal number, ExitInterrupted for SIGINT.
+func (e *InterruptedError) ExitCode() int {
+	if sig, ok := e.Signal.(syscall.Signal); ok {
+		return 128 + int(sig)
+	}
+	return ExitInterrupted
+}
@@ -0,0 +1,13 @@
+package fabricator_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestFabricator(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Fabricator Suite")
+}
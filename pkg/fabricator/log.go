@@ -0,0 +1,72 @@
+package fabricator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+)
+
+// LogFormats are the formats selected by the --log-format flag of
+// RootOptions.
+var LogFormats = []string{"text", "json"}
+
+const (
+	// VerbosityEnv is the environment variable passing the --verbosity flag of
+	// fabricator on to plugins.
+	VerbosityEnv = "FABRICATOR_VERBOSITY"
+	// LogFormatEnv is the environment variable passing the --log-format flag of
+	// fabricator on to plugins.
+	LogFormatEnv = "FABRICATOR_LOG_FORMAT"
+)
+
+// LogLevel returns the level logged at verbosity: warnings and errors at 0,
+// info at 1, debug at 2, and four levels below the previous one for every
+// further step.
+func LogLevel(verbosity int) slog.Level {
+	return slog.LevelWarn - slog.Level(4*verbosity)
+}
+
+// NewLogger returns a logger writing records of the level of verbosity (see
+// LogLevel) to w in format, one of LogFormats.
+func NewLogger(w io.Writer, verbosity int, format string) (*slog.Logger, error) {
+	options := &slog.HandlerOptions{Level: LogLevel(verbosity)}
+	switch format {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(w, options)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	default:
+		return nil, fmt.Errorf("unsupported log format %q, must be text or json", format)
+	}
+}
+
+// LogEnvironment returns the environment passing verbosity and format on to
+// plugins, which read it through the FlagParser.
+func LogEnvironment(verbosity int, format string) Environment {
+	env := Environment{VerbosityEnv: strconv.Itoa(verbosity)}
+	if format != "" {
+		env[LogFormatEnv] = format
+	}
+	return env
+}
+
+// discardLogger drops all records.
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.Level(1 << 20)}))
+
+type loggerKey struct{}
+
+// ContextWithLogger returns a copy of ctx carrying logger.
+func ContextWithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// LoggerFromContext returns the logger carried by ctx, or a logger dropping
+// all records if there is none.
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok && logger != nil {
+		return logger
+	}
+	return discardLogger
+}
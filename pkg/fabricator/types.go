@@ -2,7 +2,9 @@ package fabricator
 
 import (
 	"bytes"
+	"context"
 	"io"
+	"log/slog"
 	"os"
 	"syscall"
 
@@ -28,6 +30,17 @@ type IOStreams struct {
 	Out io.Writer
 	// ErrOut think, os.Stderr
 	ErrOut io.Writer
+	// Logger receives the log records; nil drops them. Use Log to access it.
+	Logger *slog.Logger
+}
+
+// Log returns the Logger of the streams, or a logger dropping all records if
+// there is none.
+func (s IOStreams) Log() *slog.Logger {
+	if s.Logger == nil {
+		return discardLogger
+	}
+	return s.Logger
 }
 
 // NewTestIOStreams returns a valid IOStreams and in, out, errout buffers for unit tests
@@ -77,24 +90,65 @@ type OptionProvider interface {
 
 // RootOptions defines a common set of options for all plugins
 type RootOptions struct {
+	ConfigFile     string
 	FabricatorFile string
 	RootDirectory  string
 	PluginPath     string
+	Verbosity      int
+	LogFormat      string
 	Help           bool
 	FlagParser     FlagParser
 }
 
-// RegisterOptions implements the OptionsProvider interface
+// RegisterOptions implements the OptionsProvider interface. Flags flagset
+// already defines take precedence, e.g. a --version of a plugin with the
+// shorthand -v, which --verbosity then goes without; options whose flag is
+// taken keep their default.
 func (o *RootOptions) RegisterOptions(flagset *pflag.FlagSet) {
-	flagset.StringVar(&o.FabricatorFile, "fabfile", "./.fabricator.yml", "fab-file to load")
-	flagset.StringVar(&o.RootDirectory, "rootdir", "./", "root directory for all file operations")
-	flagset.StringVarP(&o.PluginPath, "plugin-path", "p", "./", "path extension where plugins will be loaded from")
-	flagset.BoolP("help", "h", false, "Help for")
+	flags := pflag.NewFlagSet("", pflag.ContinueOnError)
+	flags.StringVar(&o.ConfigFile, "config", "", "config file (YAML, TOML, JSON or plain) to read flag values from")
+	flags.StringVar(&o.FabricatorFile, "fabfile", "./.fabricator.yml", "fab-file to load")
+	flags.StringVar(&o.RootDirectory, "rootdir", "./", "root directory for all file operations")
+	flags.StringVarP(&o.PluginPath, "plugin-path", "p", "./", "path extension where plugins will be loaded from")
+	flags.CountVarP(&o.Verbosity, "verbosity", "v", "log verbosity, repeatable: warnings and errors are logged by default, -v also logs info, -vv also debug")
+	flags.StringVar(&o.LogFormat, "log-format", "text", "log format, one of text or json")
+	flags.BoolP("help", "h", false, "Help for")
+
+	flags.VisitAll(func(f *pflag.Flag) {
+		if flagset.Lookup(f.Name) != nil {
+			return
+		}
+		if f.Shorthand != "" && flagset.ShorthandLookup(f.Shorthand) != nil {
+			f.Shorthand = ""
+		}
+		flagset.AddFlag(f)
+	})
 }
 
 // FlagParser defines the signature for a function to parse commandline flags. It exists so that cobra's flag parsing can be less magical and give control over when and what is actually parsed
 type FlagParser func(cmd *cobra.Command) error
 
+type argsKey struct{}
+
+// ContextWithArgs returns a copy of ctx carrying args, the commandline a
+// FlagParser reads for commands whose flags cobra did not parse, in place of
+// the arguments of the process.
+func ContextWithArgs(ctx context.Context, args []string) context.Context {
+	return context.WithValue(ctx, argsKey{}, args)
+}
+
+// ArgsFromContext returns the commandline carried by ctx, or the arguments of
+// the process without the name of the executable if there is none. ctx may be
+// nil, like the context of a command which is not executed.
+func ArgsFromContext(ctx context.Context) []string {
+	if ctx != nil {
+		if args, ok := ctx.Value(argsKey{}).([]string); ok {
+			return args
+		}
+	}
+	return os.Args[1:]
+}
+
 // Typed for the fabricator config file
 
 type FabricatorConfig struct {
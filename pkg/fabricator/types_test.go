@@ -0,0 +1,74 @@
+package fabricator_test
+
+import (
+	"testing"
+
+	"code.cestus.io/tools/fabricator/pkg/fabricator"
+	"github.com/spf13/pflag"
+)
+
+func TestRootOptionsRegisterOptions(t *testing.T) {
+	tests := []struct {
+		name            string
+		define          func(flagset *pflag.FlagSet)
+		args            []string
+		expectVerbosity int
+		expectConfig    string
+		expectShorthand string
+	}{
+		{
+			name:            "all flags are registered",
+			args:            []string{"-vv", "--config", "fab.yaml"},
+			expectVerbosity: 2,
+			expectConfig:    "fab.yaml",
+			expectShorthand: "v",
+		},
+		{
+			name:            "verbosity is counted",
+			args:            []string{"-v", "--verbosity=3", "-v"},
+			expectVerbosity: 4,
+			expectShorthand: "v",
+		},
+		{
+			name: "a defined shorthand is kept",
+			define: func(flagset *pflag.FlagSet) {
+				flagset.BoolP("version", "v", false, "")
+			},
+			args:            []string{"-v", "--verbosity=2"},
+			expectVerbosity: 2,
+		},
+		{
+			name: "a defined flag is kept",
+			define: func(flagset *pflag.FlagSet) {
+				flagset.String("config", "", "")
+				flagset.IntP("verbosity", "v", 0, "")
+			},
+			args:            []string{"-v", "2", "--config", "fab.yaml"},
+			expectShorthand: "v",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flagset := pflag.NewFlagSet("plugin", pflag.ContinueOnError)
+			if test.define != nil {
+				test.define(flagset)
+			}
+
+			var o fabricator.RootOptions
+			o.RegisterOptions(flagset)
+			if err := flagset.Parse(test.args); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if o.Verbosity != test.expectVerbosity || o.ConfigFile != test.expectConfig {
+				t.Errorf("want verbosity %d and config %q, have %d and %q", test.expectVerbosity, test.expectConfig, o.Verbosity, o.ConfigFile)
+			}
+			if f := flagset.Lookup("verbosity"); f == nil {
+				t.Error("want --verbosity defined")
+			} else if f.Shorthand != test.expectShorthand {
+				t.Errorf("want shorthand %q, have %q", test.expectShorthand, f.Shorthand)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+// Package ffauto provides a config file parser which picks the format of the
+// config file by its extension or, failing that, by its content.
+package ffauto
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"code.cestus.io/tools/fabricator/pkg/ff"
+	"code.cestus.io/tools/fabricator/pkg/ff/fftoml"
+	"code.cestus.io/tools/fabricator/pkg/ff/ffyaml"
+)
+
+// Parser is a parser which delegates to ffyaml.Parser, fftoml.Parser,
+// ff.JSONParser or ff.PlainParser. If the reader has a name, like the *os.File
+// ff.Parse passes, the format is chosen by its extension (".yaml", ".yml",
+// ".toml", ".json", ".conf"). Otherwise, or for any other extension, the format
+// is guessed from the content.
+func Parser(r io.Reader, set func(name, value string) error) error {
+	return ElementParser(r, set, set)
+}
+
+// ElementParser is a ff.ConfigFileElementParser which picks the format like
+// Parser, and delegates to ffyaml.ElementParser, fftoml.ElementParser,
+// ff.JSONElementParser or ff.PlainParser.
+func ElementParser(r io.Reader, set, setElement func(name, value string) error) error {
+	if named, ok := r.(interface{ Name() string }); ok {
+		if p := elementParserForFile(named.Name()); p != nil {
+			return p(r, set, setElement)
+		}
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return sniff(data)(bytes.NewReader(data), set, setElement)
+}
+
+// ParserForFile returns the parser for the extension of filename, or nil if
+// the extension is unknown.
+func ParserForFile(filename string) ff.ConfigFileParser {
+	return parser(elementParserForFile(filename))
+}
+
+func elementParserForFile(filename string) ff.ConfigFileElementParser {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		return ffyaml.ElementParser
+	case ".toml":
+		return fftoml.ElementParser
+	case ".json":
+		return ff.JSONElementParser
+	case ".conf":
+		return plainParser
+	default:
+		return nil
+	}
+}
+
+var (
+	tomlLine = regexp.MustCompile(`^(\[[^\]]+\]|[A-Za-z0-9_.\-"']+\s*=)`)
+	yamlLine = regexp.MustCompile(`^(---|-\s|[A-Za-z0-9_.\-"']+:(\s|$))`)
+)
+
+// Sniff guesses the format of the config file content from its first
+// significant line and returns the matching parser. Content which looks like
+// neither JSON, TOML nor YAML is handed to ff.PlainParser.
+func Sniff(data []byte) ff.ConfigFileParser {
+	return parser(sniff(data))
+}
+
+func sniff(data []byte) ff.ConfigFileElementParser {
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' {
+			continue // skip empties and comments, all formats agree on them
+		}
+
+		switch {
+		case line[0] == '{':
+			return ff.JSONElementParser
+		case tomlLine.MatchString(line):
+			return fftoml.ElementParser
+		case yamlLine.MatchString(line):
+			return ffyaml.ElementParser
+		default:
+			return plainParser
+		}
+	}
+	return plainParser
+}
+
+// plainParser is ff.PlainParser, which knows no arrays or maps.
+func plainParser(r io.Reader, set, _ func(name, value string) error) error {
+	return ff.PlainParser(r, set)
+}
+
+// parser returns p as a ff.ConfigFileParser, which passes elements to set.
+func parser(p ff.ConfigFileElementParser) ff.ConfigFileParser {
+	if p == nil {
+		return nil
+	}
+	return func(r io.Reader, set func(name, value string) error) error {
+		return p(r, set, set)
+	}
+}
@@ -0,0 +1,13 @@
+package ffauto_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestFfauto(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Ffauto Suite")
+}
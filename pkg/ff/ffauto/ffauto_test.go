@@ -0,0 +1,76 @@
+package ffauto_test
+
+import (
+	"testing"
+
+	"code.cestus.io/tools/fabricator/pkg/ff"
+	"code.cestus.io/tools/fabricator/pkg/ff/ffauto"
+	"code.cestus.io/tools/fabricator/pkg/ff/fftest"
+)
+
+func TestParser(t *testing.T) {
+	t.Parallel()
+
+	for _, testcase := range []struct {
+		name string
+		file string
+		want fftest.Vars
+	}{
+		{
+			name: "YAML by extension",
+			file: "testdata/basic.yaml",
+			want: fftest.Vars{S: "hello", I: 10},
+		},
+		{
+			name: "TOML by extension",
+			file: "testdata/basic.toml",
+			want: fftest.Vars{S: "hello", I: 10},
+		},
+		{
+			name: "JSON by extension",
+			file: "testdata/basic.json",
+			want: fftest.Vars{S: "hello", I: 10},
+		},
+		{
+			name: "plain by extension",
+			file: "testdata/basic.conf",
+			want: fftest.Vars{S: "hello", I: 10},
+		},
+		{
+			name: "YAML by content",
+			file: "testdata/yaml",
+			want: fftest.Vars{S: "hello", I: 10},
+		},
+		{
+			name: "TOML by content",
+			file: "testdata/toml",
+			want: fftest.Vars{S: "hello", I: 10},
+		},
+		{
+			name: "JSON by content",
+			file: "testdata/json",
+			want: fftest.Vars{S: "hello", I: 10},
+		},
+		{
+			name: "plain by content",
+			file: "testdata/plain",
+			want: fftest.Vars{S: "hello", I: 10},
+		},
+		{
+			name: "errors of the chosen parser",
+			file: "testdata/bad.yml",
+			want: fftest.Vars{WantParseErrorString: "error parsing YAML config"},
+		},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			fs, vars := fftest.Pair()
+			vars.ParseError = ff.Parse(fs, []string{},
+				ff.WithConfigFile(testcase.file),
+				ff.WithConfigFileParser(ffauto.Parser),
+			)
+			if err := fftest.Compare(&testcase.want, vars); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -1,19 +1,42 @@
 package ffpflag
 
 import (
+	"encoding/csv"
 	"flag"
+	"strings"
 
+	"code.cestus.io/tools/fabricator/pkg/ff"
 	"github.com/spf13/pflag"
 )
 
+// RequiredAnnotation is the annotation cobra's MarkFlagRequired puts on a
+// flag. Parse enforces it once env vars and config files have been applied,
+// which matters for commands with DisableFlagParsing, where cobra skips its own
+// check.
+const RequiredAnnotation = "cobra_annotation_bash_completion_one_required_flag"
+
 // FlagSet is an adapter that makes a pflag.FlagSet usable as a ff.FlagSet.
 // Flags declared using the P-suffixed variations of the pflag declaration
-// functions, with both short and long names, must be referred to using their
-// long names only in config files and environment variables.
+// functions, with both short and long names, may be referred to by either name
+// in config files, and by their long names only in environment variables.
+//
+// FlagSet implements ff.ElementSetter, so arrays and maps read by a
+// ff.ConfigFileElementParser are assigned element by element to slice and map
+// flags such as StringSlice and StringToString, instead of splitting every
+// element on commas again. Scalar values are split like on the commandline.
+//
+// FlagSet also implements ff.FlagInfoProvider, so deprecations, hidden flags and
+// required annotations are visible to ff.Parse. Setting a deprecated flag from
+// any source prints pflag's deprecation warning to the flag set's output.
 type FlagSet struct {
 	*pflag.FlagSet
 }
 
+var (
+	_ ff.FlagInfoProvider = (*FlagSet)(nil)
+	_ ff.ElementSetter    = (*FlagSet)(nil)
+)
+
 // NewFlagSet adapts the pflag.FlagSet to a ff.FlagSet.
 func NewFlagSet(fs *pflag.FlagSet) *FlagSet {
 	return &FlagSet{fs}
@@ -40,15 +63,92 @@ func (fs *FlagSet) VisitAll(fn func(*flag.Flag)) {
 	})
 }
 
-// Set implements ff.FlagSet. It calls pflag.FlagSet.Set directly.
+// Set implements ff.FlagSet. It calls pflag.FlagSet.Set with the long name of
+// the flag, so name may also be a shorthand.
 func (fs *FlagSet) Set(name, value string) error {
+	if pf := fs.lookup(name); pf != nil {
+		name = pf.Name
+	}
 	return fs.FlagSet.Set(name, value)
 }
 
+// SetElement implements ff.ElementSetter. Slice flags, which implement
+// pflag.SliceValue, are replaced by the first element and appended to by the
+// following ones; map flags merge one "key=value" entry per call. Other flags
+// are set like by Set.
+func (fs *FlagSet) SetElement(name, value string) error {
+	pf := fs.lookup(name)
+	if pf == nil {
+		return fs.FlagSet.Set(name, value)
+	}
+
+	if slice, ok := pf.Value.(pflag.SliceValue); ok {
+		// Going through pflag.FlagSet.Set keeps the flag marked as changed and
+		// prints deprecation warnings, so the element is assigned by a
+		// stand-in for the flag's value.
+		orig := pf.Value
+		pf.Value = sliceElement{Value: orig, slice: slice, replace: !pf.Changed}
+		defer func() { pf.Value = orig }()
+	} else if pf.Value.Type() == "stringToString" {
+		// pflag has no interface for map values, and stringToString reads its
+		// input as CSV; quoted, the entry yields exactly one element.
+		value = quoteCSV(value)
+	}
+	return fs.FlagSet.Set(pf.Name, value)
+}
+
 // Lookup implements ff.FlagSet. The returned flag.Flag is a temporary concrete
-// type constructed from the pflag.Flag.
+// type constructed from the pflag.Flag. Name may also be a shorthand.
 func (fs *FlagSet) Lookup(name string) *flag.Flag {
-	return pflag2std(fs.FlagSet.Lookup(name))
+	return pflag2std(fs.lookup(name))
+}
+
+// FlagInfo implements ff.FlagInfoProvider.
+func (fs *FlagSet) FlagInfo(name string) (ff.FlagInfo, bool) {
+	pf := fs.lookup(name)
+	if pf == nil {
+		return ff.FlagInfo{}, false
+	}
+	_, required := pf.Annotations[RequiredAnnotation]
+	return ff.FlagInfo{
+		Shorthand:   pf.Shorthand,
+		Deprecated:  pf.Deprecated,
+		Hidden:      pf.Hidden,
+		Required:    required,
+		Annotations: pf.Annotations,
+	}, true
+}
+
+func (fs *FlagSet) lookup(name string) *pflag.Flag {
+	if pf := fs.FlagSet.Lookup(name); pf != nil {
+		return pf
+	}
+	if len(name) == 1 {
+		return fs.FlagSet.ShorthandLookup(name)
+	}
+	return nil
+}
+
+func quoteCSV(value string) string {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	_ = w.Write([]string{value}) // writing to a strings.Builder does not fail
+	w.Flush()
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// sliceElement is a slice flag value whose Set assigns exactly one element.
+type sliceElement struct {
+	pflag.Value
+	slice   pflag.SliceValue
+	replace bool
+}
+
+func (v sliceElement) Set(value string) error {
+	if v.replace {
+		return v.slice.Replace([]string{value})
+	}
+	return v.slice.Append(value)
 }
 
 func pflag2std(pFlag *pflag.Flag) *flag.Flag {
@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"code.cestus.io/tools/fabricator/pkg/ff"
+	"code.cestus.io/tools/fabricator/pkg/ff/ffauto"
 	"code.cestus.io/tools/fabricator/pkg/ff/ffpflag"
 	"code.cestus.io/tools/fabricator/pkg/ff/fftoml"
 	"code.cestus.io/tools/fabricator/pkg/ff/ffyaml"
@@ -183,3 +184,207 @@ func TestBasics(t *testing.T) {
 		})
 	}
 }
+
+func TestFlagInfo(t *testing.T) {
+	t.Parallel()
+
+	for _, testcase := range []struct {
+		name          string
+		env           map[string]string
+		file          string
+		args          []string
+		prepare       func(fs *pflag.FlagSet)
+		want          Vars
+		wantOut       string
+		dontWantError string
+		dontWantUsage string
+	}{
+		{
+			name: "shorthands in config file",
+			file: "testdata/5.conf",
+			args: []string{"--string=explicit"},
+			want: Vars{S: "explicit", B: true},
+		},
+		{
+			name: "deprecated flag from env var",
+			env:  map[string]string{"PF_INFO_STRING": "hello"},
+			prepare: func(fs *pflag.FlagSet) {
+				_ = fs.MarkDeprecated("string", "use --bool instead")
+			},
+			want:    Vars{S: "hello"},
+			wantOut: "Flag --string has been deprecated, use --bool instead",
+		},
+		{
+			name: "required annotation missing",
+			prepare: func(fs *pflag.FlagSet) {
+				_ = fs.SetAnnotation("f", ffpflag.RequiredAnnotation, []string{"true"})
+			},
+			want: Vars{WantParseErrorString: `missing required flags "f"`},
+		},
+		{
+			name: "required annotation satisfied by env var",
+			env:  map[string]string{"PF_INFO_F": "1.5"},
+			prepare: func(fs *pflag.FlagSet) {
+				_ = fs.SetAnnotation("f", ffpflag.RequiredAnnotation, []string{"true"})
+			},
+			want: Vars{F: 1.5},
+		},
+		{
+			name: "hidden flag from env var is left out of the usage",
+			env:  map[string]string{"PF_INFO_STRING": "hello"},
+			prepare: func(fs *pflag.FlagSet) {
+				_ = fs.MarkHidden("string")
+			},
+			want:          Vars{S: "hello"},
+			dontWantUsage: "--string",
+		},
+		{
+			name: "visible flags are suggested",
+			file: "testdata/6.conf",
+			want: Vars{WantParseErrorString: `config file flag "strng" not defined in flag set (testdata/6.conf:1); did you mean "string"?`},
+		},
+		{
+			name: "hidden flags are not suggested",
+			file: "testdata/6.conf",
+			prepare: func(fs *pflag.FlagSet) {
+				_ = fs.MarkHidden("string")
+			},
+			want:          Vars{WantParseErrorString: `config file flag "strng" not defined in flag set (testdata/6.conf:1)`},
+			dontWantError: "did you mean",
+		},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			opts := []ff.Option{ff.WithEnvVarPrefix("PF_INFO")}
+			if testcase.file != "" {
+				opts = append(opts, ff.WithConfigFile(testcase.file), ff.WithConfigFileParser(ff.PlainParser))
+			}
+
+			for k, v := range testcase.env {
+				defer os.Setenv(k, os.Getenv(k))
+				os.Setenv(k, v)
+			}
+
+			fs, vars := Pair()
+			var out strings.Builder
+			fs.SetOutput(&out)
+			if testcase.prepare != nil {
+				testcase.prepare(fs)
+			}
+			vars.ParseError = ff.Parse(ffpflag.NewFlagSet(fs), testcase.args, opts...)
+			vars.Args = fs.Args()
+			if err := Compare(&testcase.want, vars); err != nil {
+				t.Fatal(err)
+			}
+			if testcase.dontWantError != "" && vars.ParseError != nil && strings.Contains(vars.ParseError.Error(), testcase.dontWantError) {
+				t.Fatalf("error: do not want %q, have %q", testcase.dontWantError, vars.ParseError)
+			}
+			if !strings.Contains(out.String(), testcase.wantOut) {
+				t.Fatalf("output: want %q, have %q", testcase.wantOut, out.String())
+			}
+			if usage := fs.FlagUsages(); testcase.dontWantUsage != "" && strings.Contains(usage, testcase.dontWantUsage) {
+				t.Fatalf("usage: do not want %q, have %q", testcase.dontWantUsage, usage)
+			}
+		})
+	}
+}
+
+func TestCollections(t *testing.T) {
+	t.Parallel()
+
+	for _, testcase := range []struct {
+		name       string
+		env        map[string]string
+		file       string
+		parser     ff.ConfigFileElementParser
+		opts       []ff.Option
+		wantTags   []string
+		wantLabels map[string]string
+	}{
+		{
+			name:       "defaults",
+			wantTags:   []string{"default"},
+			wantLabels: map[string]string{"default": "true"},
+		},
+		{
+			name:       "YAML sequences and mappings",
+			file:       "testdata/7.yaml",
+			parser:     ffyaml.ElementParser,
+			wantTags:   []string{"a, b", "c"},
+			wantLabels: map[string]string{"team": "core", "note": "x=y, z"},
+		},
+		{
+			name:       "JSON arrays and objects",
+			file:       "testdata/8.json",
+			parser:     ff.JSONElementParser,
+			wantTags:   []string{"a, b", "c"},
+			wantLabels: map[string]string{"team": "core", "note": "x=y, z"},
+		},
+		{
+			name:       "TOML arrays and inline tables",
+			file:       "testdata/9.toml",
+			parser:     fftoml.ElementParser,
+			wantTags:   []string{"a, b", "c"},
+			wantLabels: map[string]string{"team": "core", "note": "x=y, z"},
+		},
+		{
+			name:       "YAML scalars keep pflag parsing",
+			file:       "testdata/10.yaml",
+			parser:     ffyaml.ElementParser,
+			wantTags:   []string{"a", "b"},
+			wantLabels: map[string]string{"team": "core", "tier": "1"},
+		},
+		{
+			name:       "TOML scalars keep pflag parsing",
+			file:       "testdata/12.toml",
+			parser:     fftoml.ElementParser,
+			wantTags:   []string{"a", "b"},
+			wantLabels: map[string]string{"team": "core", "tier": "1"},
+		},
+		{
+			name:       "plain config file values keep pflag parsing",
+			file:       "testdata/11.conf",
+			parser:     ffauto.ElementParser,
+			wantTags:   []string{"a", "b"},
+			wantLabels: map[string]string{"team": "core", "tier": "1"},
+		},
+		{
+			name:       "env var without split keeps pflag parsing",
+			env:        map[string]string{"PF_COL_TAGS": "a,b", "PF_COL_LABELS": "team=core,tier=1"},
+			wantTags:   []string{"a", "b"},
+			wantLabels: map[string]string{"team": "core", "tier": "1"},
+		},
+		{
+			name:       "env var split tokens are single elements",
+			env:        map[string]string{"PF_COL_TAGS": "a;b, c"},
+			opts:       []ff.Option{ff.WithEnvVarSplit(";")},
+			wantTags:   []string{"a", "b, c"},
+			wantLabels: map[string]string{"default": "true"},
+		},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			opts := append([]ff.Option{ff.WithEnvVarPrefix("PF_COL")}, testcase.opts...)
+			if testcase.file != "" {
+				opts = append(opts, ff.WithConfigFile(testcase.file), ff.WithConfigFileElementParser(testcase.parser))
+			}
+
+			for k, v := range testcase.env {
+				defer os.Setenv(k, os.Getenv(k))
+				os.Setenv(k, v)
+			}
+
+			fs := pflag.NewFlagSet("ffpflag_test", pflag.ContinueOnError)
+			tags := fs.StringSlice("tags", []string{"default"}, "a string slice")
+			labels := fs.StringToString("labels", map[string]string{"default": "true"}, "a string map")
+
+			if err := ff.Parse(ffpflag.NewFlagSet(fs), []string{}, opts...); err != nil {
+				t.Fatalf("want no parse error, have error: %v", err)
+			}
+			if !reflect.DeepEqual(testcase.wantTags, *tags) {
+				t.Fatalf("tags: want %q, have %q", testcase.wantTags, *tags)
+			}
+			if !reflect.DeepEqual(testcase.wantLabels, *labels) {
+				t.Fatalf("labels: want %q, have %q", testcase.wantLabels, *labels)
+			}
+		})
+	}
+}
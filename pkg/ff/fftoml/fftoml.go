@@ -4,6 +4,7 @@ package fftoml
 import (
 	"fmt"
 	"io"
+	"sort"
 	"strconv"
 
 	"code.cestus.io/tools/fabricator/pkg/ff"
@@ -16,10 +17,18 @@ func Parser(r io.Reader, set func(name, value string) error) error {
 	return New().Parse(r, set)
 }
 
+// ElementParser is a ff.ConfigFileElementParser for the TOML file format. It
+// reads files like Parser, but passes the elements of arrays and the entries
+// of inline tables to setElement.
+func ElementParser(r io.Reader, set, setElement func(name, value string) error) error {
+	return New().ParseElements(r, set, setElement)
+}
+
 // ConfigFileParser is a parser for the TOML file format. Flags and their values
 // are read from the key/value pairs defined in the config file.
 // Nested tables and keys are concatenated with a delimiter to derive the
-// relevant flag name.
+// relevant flag name. Inline tables, like labels = { app = "api" }, are map
+// values instead, set once per key, in sorted order, with "key=value".
 type ConfigFileParser struct {
 	delimiter string
 }
@@ -36,12 +45,22 @@ func New(opts ...Option) (c ConfigFileParser) {
 // Parse parses the provided io.Reader as a TOML file and uses the provided set function
 // to set flag names derived from the tables names and their key/value pairs.
 func (c ConfigFileParser) Parse(r io.Reader, set func(name, value string) error) error {
+	return c.ParseElements(r, set, set)
+}
+
+// ParseElements is like Parse, but passes the elements of arrays and the entries
+// of inline tables to setElement.
+func (c ConfigFileParser) ParseElements(r io.Reader, set, setElement func(name, value string) error) error {
 	tree, err := toml.LoadReader(r)
 	if err != nil {
 		return ParseError{Inner: err}
 	}
 
-	return parseTree(tree, "", c.delimiter, set)
+	var undefined ff.UndefinedFlagCollector
+	if err := parseTree(tree, "", c.delimiter, &undefined, set, setElement); err != nil {
+		return err
+	}
+	return undefined.Err()
 }
 
 // Option is a function which changes the behavior of the TOML config file parser.
@@ -65,7 +84,7 @@ func WithTableDelimiter(d string) Option {
 	}
 }
 
-func parseTree(tree *toml.Tree, parent, delimiter string, set func(name, value string) error) error {
+func parseTree(tree *toml.Tree, parent, delimiter string, undefined *ff.UndefinedFlagCollector, set, setElement func(name, value string) error) error {
 	for _, key := range tree.Keys() {
 		name := key
 		if parent != "" {
@@ -73,16 +92,34 @@ func parseTree(tree *toml.Tree, parent, delimiter string, set func(name, value s
 		}
 		switch t := tree.Get(key).(type) {
 		case *toml.Tree:
-			if err := parseTree(t, name, delimiter, set); err != nil {
-				return err
+			if !t.Inline() {
+				if err := parseTree(t, name, delimiter, undefined, set, setElement); err != nil {
+					return err
+				}
+				break
+			}
+			values, err := tableToStrs(t)
+			if err != nil {
+				return ParseError{Inner: err}
+			}
+			line := tree.GetPosition(key).Line
+			for _, value := range values {
+				if err = undefined.Set(setElement, name, value, line); err != nil {
+					return err
+				}
 			}
 		case interface{}:
-			values, err := valsToStrs(t)
+			values, elements, err := valsToStrs(t)
 			if err != nil {
 				return ParseError{Inner: err}
 			}
+			setValue := set
+			if elements {
+				setValue = setElement
+			}
+			line := tree.GetPosition(key).Line
 			for _, value := range values {
-				if err = set(name, value); err != nil {
+				if err = undefined.Set(setValue, name, value, line); err != nil {
 					return err
 				}
 			}
@@ -91,26 +128,44 @@ func parseTree(tree *toml.Tree, parent, delimiter string, set func(name, value s
 	return nil
 }
 
-func valsToStrs(val interface{}) ([]string, error) {
+// valsToStrs returns the values of val, and whether they are elements of an
+// array.
+func valsToStrs(val interface{}) ([]string, bool, error) {
 	if vals, ok := val.([]interface{}); ok {
 		ss := make([]string, len(vals))
 		for i := range vals {
 			s, err := valToStr(vals[i])
 			if err != nil {
-				return nil, err
+				return nil, false, err
 			}
 			ss[i] = s
 		}
-		return ss, nil
+		return ss, true, nil
 	}
 	s, err := valToStr(val)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
-	return []string{s}, nil
+	return []string{s}, false, nil
 
 }
 
+// tableToStrs returns the entries of the inline table as "key=value", in sorted
+// order.
+func tableToStrs(tree *toml.Tree) ([]string, error) {
+	keys := tree.Keys()
+	sort.Strings(keys)
+	ss := make([]string, len(keys))
+	for i, k := range keys {
+		s, err := valToStr(tree.Get(k))
+		if err != nil {
+			return nil, err
+		}
+		ss[i] = k + "=" + s
+	}
+	return ss, nil
+}
+
 func valToStr(val interface{}) (string, error) {
 	switch v := val.(type) {
 	case string:
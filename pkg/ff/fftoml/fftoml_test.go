@@ -36,6 +36,11 @@ func TestParser(t *testing.T) {
 				X: []string{"1", "a", "👍"},
 			},
 		},
+		{
+			name: "undefined keys",
+			file: "testdata/undefined.toml",
+			want: fftest.Vars{WantParseErrorString: `config file flag "table.bb" not defined in flag set (testdata/undefined.toml:4)`},
+		},
 		{
 			name: "bad TOML file",
 			file: "testdata/bad.toml",
@@ -4,6 +4,7 @@ package ffyaml
 import (
 	"fmt"
 	"io"
+	"sort"
 	"strconv"
 
 	"code.cestus.io/tools/fabricator/pkg/ff"
@@ -11,44 +12,100 @@ import (
 )
 
 // Parser is a parser for YAML file format. Flags and their values are read
-// from the key/value pairs defined in the config file.
+// from the key/value pairs defined in the config file. Sequences set the flag
+// once per element, and mappings once per key, in sorted order, with
+// "key=value".
 func Parser(r io.Reader, set func(name, value string) error) error {
-	var m map[string]interface{}
+	return ElementParser(r, set, set)
+}
+
+// ElementParser is a ff.ConfigFileElementParser for the YAML file format. It
+// reads files like Parser, but passes the elements of sequences and the entries
+// of mappings to setElement.
+func ElementParser(r io.Reader, set, setElement func(name, value string) error) error {
+	var doc yaml.Node
 	d := yaml.NewDecoder(r)
-	if err := d.Decode(&m); err != nil && err != io.EOF {
+	if err := d.Decode(&doc); err != nil && err != io.EOF {
 		return ParseError{err}
 	}
-	for key, val := range m {
-		values, err := valsToStrs(val)
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	m := doc.Content[0]
+	if m.Kind != yaml.MappingNode {
+		var v interface{}
+		if err := m.Decode(&v); err != nil {
+			return ParseError{err}
+		}
+		if v == nil {
+			return nil
+		}
+		return ParseError{fmt.Errorf("line %d: expected a mapping of flag names to values", m.Line)}
+	}
+
+	var undefined ff.UndefinedFlagCollector
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		keyNode, valNode := m.Content[i], m.Content[i+1]
+		var key string
+		if err := keyNode.Decode(&key); err != nil {
+			return ParseError{err}
+		}
+		var val interface{}
+		if err := valNode.Decode(&val); err != nil {
+			return ParseError{err}
+		}
+		values, elements, err := valsToStrs(val)
 		if err != nil {
 			return ParseError{err}
 		}
+		setValue := set
+		if elements {
+			setValue = setElement
+		}
 		for _, value := range values {
-			if err := set(key, value); err != nil {
+			if err := undefined.Set(setValue, key, value, keyNode.Line); err != nil {
 				return err
 			}
 		}
 	}
-	return nil
+	return undefined.Err()
 }
 
-func valsToStrs(val interface{}) ([]string, error) {
+// valsToStrs returns the values of val, and whether they are elements of a
+// sequence or a mapping.
+func valsToStrs(val interface{}) ([]string, bool, error) {
+	if m, ok := val.(map[string]interface{}); ok {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		ss := make([]string, len(keys))
+		for i, k := range keys {
+			s, err := valToStr(m[k])
+			if err != nil {
+				return nil, false, err
+			}
+			ss[i] = k + "=" + s
+		}
+		return ss, true, nil
+	}
 	if vals, ok := val.([]interface{}); ok {
 		ss := make([]string, len(vals))
 		for i := range vals {
 			s, err := valToStr(vals[i])
 			if err != nil {
-				return nil, err
+				return nil, false, err
 			}
 			ss[i] = s
 		}
-		return ss, nil
+		return ss, true, nil
 	}
 	s, err := valToStr(val)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
-	return []string{s}, nil
+	return []string{s}, false, nil
 
 }
 
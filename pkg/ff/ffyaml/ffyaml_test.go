@@ -52,6 +52,17 @@ func TestParser(t *testing.T) {
 			file: "testdata/line_break_array.yaml",
 			want: fftest.Vars{X: []string{"first string", "second string", "third"}},
 		},
+		{
+			name: "undefined keys",
+			file: "testdata/undefined.yaml",
+			want: fftest.Vars{WantParseErrorString: `config file flag "bb" not defined in flag set (testdata/undefined.yaml:2); did you mean "b"?` + "\n" +
+				`config file flag "undefined" not defined in flag set (testdata/undefined.yaml:3)`},
+		},
+		{
+			name: "mappings",
+			file: "testdata/map.yaml",
+			want: fftest.Vars{X: []string{"a=1", "b=2"}},
+		},
 		{
 			name: "unquoted strings in arrays",
 			file: "testdata/unquoted_string_array.yaml",
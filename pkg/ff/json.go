@@ -1,54 +1,132 @@
 package ff
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"sort"
 	"strconv"
 )
 
 // JSONParser is a parser for config files in JSON format. Input should be
 // an object. The object's keys are treated as flag names, and the object's
 // values as flag values. If the value is an array, the flag will be set
-// multiple times.
+// multiple times. If the value is an object, the flag will be set once per
+// key, in sorted order, with "key=value".
 func JSONParser(r io.Reader, set func(name, value string) error) error {
-	var m map[string]interface{}
-	d := json.NewDecoder(r)
+	return JSONElementParser(r, set, set)
+}
+
+// JSONElementParser is a ConfigFileElementParser for config files in JSON
+// format. It reads them like JSONParser, but passes the elements of arrays and
+// the entries of objects to setElement.
+func JSONElementParser(r io.Reader, set, setElement func(name, value string) error) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return JSONParseError{Inner: err}
+	}
+	d := json.NewDecoder(bytes.NewReader(data))
 	d.UseNumber() // must set UseNumber for stringifyValue to work
-	if err := d.Decode(&m); err != nil {
+
+	parseError := func(err error) error {
+		// Running out of input while reading the object is reported uniformly,
+		// regardless of which token the decoder was expecting.
+		var syntaxErr *json.SyntaxError
+		if err == io.EOF || errors.As(err, &syntaxErr) && syntaxErr.Offset >= int64(len(bytes.TrimSpace(data))) {
+			err = io.ErrUnexpectedEOF
+		}
 		return JSONParseError{Inner: err}
 	}
-	for key, val := range m {
-		values, err := stringifySlice(val)
+
+	// Walk the top-level object token by token, so that keys are visited in
+	// file order and their line can be reported.
+	if err := expectDelim(d, '{'); err != nil {
+		return parseError(err)
+	}
+	var undefined UndefinedFlagCollector
+	for d.More() {
+		tok, err := d.Token()
+		if err != nil {
+			return parseError(err)
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return JSONParseError{Inner: fmt.Errorf("unexpected token %v", tok)}
+		}
+		line := 1 + bytes.Count(data[:d.InputOffset()], []byte("\n"))
+
+		var val interface{}
+		if err := d.Decode(&val); err != nil {
+			return parseError(err)
+		}
+		values, elements, err := stringifySlice(val)
 		if err != nil {
 			return JSONParseError{Inner: err}
 		}
+		setValue := set
+		if elements {
+			setValue = setElement
+		}
 		for _, value := range values {
-			if err := set(key, value); err != nil {
+			if err := undefined.Set(setValue, key, value, line); err != nil {
 				return err
 			}
 		}
 	}
+	if err := expectDelim(d, '}'); err != nil {
+		return parseError(err)
+	}
+	return undefined.Err()
+}
+
+func expectDelim(d *json.Decoder, delim json.Delim) error {
+	tok, err := d.Token()
+	if err != nil {
+		return err
+	}
+	if tok != delim {
+		return fmt.Errorf("expected %v, found %v", delim, tok)
+	}
 	return nil
 }
 
-func stringifySlice(val interface{}) ([]string, error) {
+// stringifySlice returns the values of val, and whether they are elements of
+// an array or an object.
+func stringifySlice(val interface{}) ([]string, bool, error) {
+	if m, ok := val.(map[string]interface{}); ok {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		ss := make([]string, len(keys))
+		for i, k := range keys {
+			s, err := stringifyValue(m[k])
+			if err != nil {
+				return nil, false, err
+			}
+			ss[i] = k + "=" + s
+		}
+		return ss, true, nil
+	}
 	if vals, ok := val.([]interface{}); ok {
 		ss := make([]string, len(vals))
 		for i := range vals {
 			s, err := stringifyValue(vals[i])
 			if err != nil {
-				return nil, err
+				return nil, false, err
 			}
 			ss[i] = s
 		}
-		return ss, nil
+		return ss, true, nil
 	}
 	s, err := stringifyValue(val)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
-	return []string{s}, nil
+	return []string{s}, false, nil
 }
 
 func stringifyValue(val interface{}) (string, error) {
@@ -36,6 +36,13 @@ func TestJSONParser(t *testing.T) {
 			file: "testdata/value_arrays.json",
 			want: fftest.Vars{S: "bb", I: 12, B: true, D: 5 * time.Second, X: []string{"a", "B", "👍"}},
 		},
+		{
+			name: "undefined keys",
+			args: []string{},
+			file: "testdata/undefined.json",
+			want: fftest.Vars{WantParseErrorString: `config file flag "bb" not defined in flag set (testdata/undefined.json:3); did you mean "b"?` + "\n" +
+				`config file flag "undefined" not defined in flag set (testdata/undefined.json:4)`},
+		},
 		{
 			name: "bad JSON file",
 			args: []string{},
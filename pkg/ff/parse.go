@@ -2,6 +2,7 @@ package ff
 
 import (
 	"bufio"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -23,6 +24,40 @@ type FlagSet interface {
 	Lookup(name string) *flag.Flag
 }
 
+// ElementSetter is an optional interface a FlagSet can implement when some of
+// its flags split a value passed to Set into several elements, like pflag's
+// slice and map flags. Parse calls SetElement instead of Set for every element
+// of a config file array and every "key=value" entry of a config file map
+// reported by a ConfigFileElementParser, and for every token produced by
+// WithEnvVarSplit, and expects it to be assigned as a single element, without
+// being split again. Scalar values are always passed to Set.
+type ElementSetter interface {
+	SetElement(name, value string) error
+}
+
+// FlagInfo carries metadata about a flag that flag.Flag has no room for.
+type FlagInfo struct {
+	// Shorthand is the single letter alias of the flag, if any.
+	Shorthand string
+	// Deprecated is the deprecation message of the flag, if it is deprecated.
+	Deprecated string
+	// Hidden reports whether the flag is left out of usage and docs.
+	Hidden bool
+	// Required reports whether the flag must be set by one of the sources.
+	Required bool
+	// Annotations are arbitrary key/values attached to the flag.
+	Annotations map[string][]string
+}
+
+// FlagInfoProvider is an optional interface a FlagSet can implement to
+// provide FlagInfo for its flags. Parse enforces Required flags like flags
+// passed to WithRequired, and does not suggest Hidden flags for undefined
+// config file keys. ff renders no usage or docs itself, so leaving Hidden
+// flags out of them is up to their renderer, e.g. cobra for pflag flags.
+type FlagInfoProvider interface {
+	FlagInfo(name string) (FlagInfo, bool)
+}
+
 // Parse the flags in the flag set from the provided (presumably commandline)
 // args. Additional options may be provided to parse from a config file and/or
 // environment variables in that priority order.
@@ -32,11 +67,18 @@ func Parse(fs FlagSet, args []string, options ...Option) error {
 		option(&c)
 	}
 
+	setElement := fs.Set
+	if es, ok := fs.(ElementSetter); ok {
+		setElement = es.SetElement
+	}
+
 	// First priority: commandline flags (explicit user preference).
 	if err := fs.Parse(args); err != nil {
 		return fmt.Errorf("error parsing commandline args: %w", err)
 	}
 
+	sources := []string{"commandline args"}
+
 	provided := map[string]bool{}
 	fs.Visit(func(f *flag.Flag) {
 		provided[f.Name] = true
@@ -44,6 +86,12 @@ func Parse(fs FlagSet, args []string, options ...Option) error {
 
 	// Second priority: environment variables (session).
 	if parseEnv := c.envVarPrefix != "" || c.envVarNoPrefix; parseEnv {
+		if c.envVarNoPrefix {
+			sources = append(sources, "env vars")
+		} else {
+			sources = append(sources, fmt.Sprintf("env vars with prefix %q", strings.ToUpper(c.envVarPrefix)+"_"))
+		}
+
 		var visitErr error
 		fs.VisitAll(func(f *flag.Flag) {
 			if visitErr != nil {
@@ -64,8 +112,12 @@ func Parse(fs FlagSet, args []string, options ...Option) error {
 				return
 			}
 
+			set := fs.Set
+			if c.envVarSplit != "" {
+				set = setElement
+			}
 			for _, v := range maybeSplit(value, c.envVarSplit) {
-				if err := fs.Set(f.Name, v); err != nil {
+				if err := set(f.Name, v); err != nil {
 					visitErr = fmt.Errorf("error setting flag %q from env var %q: %w", f.Name, key, err)
 					return
 				}
@@ -92,25 +144,50 @@ func Parse(fs FlagSet, args []string, options ...Option) error {
 		switch {
 		case err == nil:
 			defer f.Close()
-			if err := c.configFileParser(f, func(name, value string) error {
-				if provided[name] {
-					return nil
-				}
+			sources = append(sources, fmt.Sprintf("config file %q", c.configFile))
+			setFromConfig := func(set func(name, value string) error) func(name, value string) error {
+				return func(name, value string) error {
+					// The flag set may resolve aliases, e.g. pflag shorthands, so
+					// priority is checked against the name the flag was defined with.
+					fl := fs.Lookup(name)
+					if fl != nil {
+						name = fl.Name
+					}
+
+					if provided[name] {
+						return nil
+					}
+
+					defined := fl != nil
+					switch {
+					case !defined && c.ignoreUndefined:
+						return nil
+					case !defined && !c.ignoreUndefined:
+						var names []string
+						infos, _ := fs.(FlagInfoProvider)
+						fs.VisitAll(func(f *flag.Flag) {
+							if infos != nil {
+								if info, ok := infos.FlagInfo(f.Name); ok && info.Hidden {
+									return
+								}
+							}
+							names = append(names, f.Name)
+						})
+						return &UndefinedFlagError{
+							Name:       name,
+							Suggestion: suggestFlag(name, names),
+							File:       c.configFile,
+						}
+					}
+
+					if err := set(name, value); err != nil {
+						return fmt.Errorf("error setting flag %q from config file: %w", name, err)
+					}
 
-				defined := fs.Lookup(name) != nil
-				switch {
-				case !defined && c.ignoreUndefined:
 					return nil
-				case !defined && !c.ignoreUndefined:
-					return fmt.Errorf("config file flag %q not defined in flag set", name)
-				}
-
-				if err := fs.Set(name, value); err != nil {
-					return fmt.Errorf("error setting flag %q from config file: %w", name, err)
 				}
-
-				return nil
-			}); err != nil {
+			}
+			if err := c.configFileParser(f, setFromConfig(fs.Set), setFromConfig(setElement)); err != nil {
 				return err
 			}
 
@@ -126,6 +203,38 @@ func Parse(fs FlagSet, args []string, options ...Option) error {
 		provided[f.Name] = true
 	})
 
+	// Checks run only once every source had a chance to set the flags.
+	required := c.required
+	if infos, ok := fs.(FlagInfoProvider); ok {
+		fs.VisitAll(func(f *flag.Flag) {
+			if info, ok := infos.FlagInfo(f.Name); ok && info.Required {
+				required = append(required, f.Name)
+			}
+		})
+	}
+
+	var missing []string
+	seen := map[string]bool{}
+	for _, name := range required {
+		if !provided[name] && !seen[name] {
+			missing = append(missing, name)
+		}
+		seen[name] = true
+	}
+	if len(missing) > 0 {
+		return MissingFlagsError{Flags: missing, Sources: sources}
+	}
+
+	var validationErrs []error
+	for _, validate := range c.validators {
+		if err := validate(fs); err != nil {
+			validationErrs = append(validationErrs, err)
+		}
+	}
+	if err := errors.Join(validationErrs...); err != nil {
+		return fmt.Errorf("error validating flags: %w", err)
+	}
+
 	return nil
 }
 
@@ -133,12 +242,14 @@ func Parse(fs FlagSet, args []string, options ...Option) error {
 type Context struct {
 	configFile             string
 	configFileFlagName     string
-	configFileParser       ConfigFileParser
+	configFileParser       ConfigFileElementParser
 	allowMissingConfigFile bool
 	envVarPrefix           string
 	envVarNoPrefix         bool
 	envVarSplit            string
 	ignoreUndefined        bool
+	required               []string
+	validators             []func(FlagSet) error
 }
 
 // Option controls some aspect of Parse behavior.
@@ -168,8 +279,20 @@ func WithConfigFileFlag(flagname string) Option {
 }
 
 // WithConfigFileParser tells Parse how to interpret the config file provided
-// via WithConfigFile or WithConfigFileFlag.
+// via WithConfigFile or WithConfigFileFlag. Every value the parser reports is
+// passed to Set.
 func WithConfigFileParser(p ConfigFileParser) Option {
+	return func(c *Context) {
+		c.configFileParser = func(r io.Reader, set, _ func(name, value string) error) error {
+			return p(r, set)
+		}
+	}
+}
+
+// WithConfigFileElementParser is like WithConfigFileParser, but elements of
+// arrays and maps in the config file are passed to SetElement, if the flag set
+// is an ElementSetter.
+func WithConfigFileElementParser(p ConfigFileElementParser) Option {
 	return func(c *Context) {
 		c.configFileParser = p
 	}
@@ -207,8 +330,8 @@ func WithEnvVarNoPrefix() Option {
 }
 
 // WithEnvVarSplit tells Parse to split environment variables on the given
-// delimiter, and to make a call to Set on the corresponding flag with each
-// split token.
+// delimiter, and to make a call to Set (or SetElement, if the flag set is an
+// ElementSetter) on the corresponding flag with each split token.
 func WithEnvVarSplit(delimiter string) Option {
 	return func(c *Context) {
 		c.envVarSplit = delimiter
@@ -217,26 +340,74 @@ func WithEnvVarSplit(delimiter string) Option {
 
 // WithIgnoreUndefined tells Parse to ignore undefined flags that it encounters
 // in config files. By default, if Parse encounters an undefined flag in a
-// config file, it will return an error. Note that this setting does not apply
-// to undefined flags passed as arguments.
+// config file, it will return an error naming every undefined key, together
+// with its position and the closest defined flag name. Note that this setting
+// does not apply to undefined flags passed as arguments.
 func WithIgnoreUndefined(ignore bool) Option {
 	return func(c *Context) {
 		c.ignoreUndefined = ignore
 	}
 }
 
+// WithRequired tells Parse that the flags with the given names must be set by
+// at least one source: commandline args, environment variables or the config
+// file. All missing flags are reported together in a MissingFlagsError once
+// every source has been applied. The option may be given more than once.
+func WithRequired(names ...string) Option {
+	return func(c *Context) {
+		c.required = append(c.required, names...)
+	}
+}
+
+// WithValidator tells Parse to call the given function with the flag set after
+// all sources have been applied and all required flags are present. Errors of
+// all validators are joined into the error returned by Parse. The option may be
+// given more than once.
+func WithValidator(validate func(FlagSet) error) Option {
+	return func(c *Context) {
+		c.validators = append(c.validators, validate)
+	}
+}
+
+// MissingFlagsError is returned by Parse when flags passed to WithRequired
+// were not set by any of the consulted sources.
+type MissingFlagsError struct {
+	// Flags are the names of the missing flags, in the order they were required.
+	Flags []string
+	// Sources describe where Parse looked for values.
+	Sources []string
+}
+
+// Error implements the error interface.
+func (e MissingFlagsError) Error() string {
+	quoted := make([]string, len(e.Flags))
+	for i, name := range e.Flags {
+		quoted[i] = fmt.Sprintf("%q", name)
+	}
+	return fmt.Sprintf("missing required flags %s (consulted %s)", strings.Join(quoted, ", "), strings.Join(e.Sources, ", "))
+}
+
 // ConfigFileParser interprets the config file represented by the reader
-// and calls the set function for each parsed flag pair.
+// and calls the set function for each parsed flag pair. Parsers for formats
+// with arrays call set once per element, and parsers for formats with maps
+// call set once per entry, formatted as "key=value".
 type ConfigFileParser func(r io.Reader, set func(name, value string) error) error
 
+// ConfigFileElementParser is a ConfigFileParser which tells elements of arrays
+// and maps apart from scalar values: it calls set once for every scalar value,
+// and setElement once per array element and once per map entry, formatted as
+// "key=value".
+type ConfigFileElementParser func(r io.Reader, set, setElement func(name, value string) error) error
+
 // PlainParser is a parser for config files in an extremely simple format. Each
 // line is tokenized as a single key/value pair. The first whitespace-delimited
 // token in the line is interpreted as the flag name, and all remaining tokens
 // are interpreted as the value. Any leading hyphens on the flag name are
 // ignored.
 func PlainParser(r io.Reader, set func(name, value string) error) error {
+	var undefined UndefinedFlagCollector
 	s := bufio.NewScanner(r)
-	for s.Scan() {
+	for lineNumber := 1; s.Scan(); lineNumber++ {
 		line := strings.TrimSpace(s.Text())
 		if line == "" {
 			continue // skip empties
@@ -261,11 +432,11 @@ func PlainParser(r io.Reader, set func(name, value string) error) error {
 			value = strings.TrimSpace(value[:i])
 		}
 
-		if err := set(name, value); err != nil {
+		if err := undefined.Set(set, name, value, lineNumber); err != nil {
 			return fmt.Errorf("PlainParser set: %w", err)
 		}
 	}
-	return nil
+	return undefined.Err()
 }
 
 var envVarReplacer = strings.NewReplacer(
@@ -1,6 +1,7 @@
 package ff_test
 
 import (
+	"errors"
 	"os"
 	"testing"
 	"time"
@@ -128,12 +129,58 @@ func TestParseBasics(t *testing.T) {
 			opts: []ff.Option{ff.WithIgnoreUndefined(false)},
 			want: fftest.Vars{WantParseErrorString: "config file flag"},
 		},
+		{
+			name: "undefined flags in file are collected",
+			file: "testdata/undefined_many.conf",
+			want: fftest.Vars{WantParseErrorString: `config file flag "bb" not defined in flag set (testdata/undefined_many.conf:2); did you mean "b"?` + "\n" +
+				`config file flag "undefined" not defined in flag set (testdata/undefined_many.conf:3)`},
+		},
 		{
 			name: "env var split comma whitespace",
 			env:  map[string]string{"TEST_PARSE_S": "one, two, three ", "TEST_PARSE_X": "one, two, three "},
 			opts: []ff.Option{ff.WithEnvVarPrefix("TEST_PARSE"), ff.WithEnvVarSplit(",")},
 			want: fftest.Vars{S: " three ", X: []string{"one", " two", " three "}},
 		},
+		{
+			name: "WithRequired satisfied by all sources",
+			env:  map[string]string{"TEST_PARSE_F": "0.5"},
+			file: "testdata/1.conf",
+			args: []string{"-x", "one"},
+			opts: []ff.Option{ff.WithEnvVarPrefix("TEST_PARSE"), ff.WithRequired("x", "f"), ff.WithRequired("s")},
+			want: fftest.Vars{S: "bar", I: 99, F: 0.5, B: true, D: time.Hour, X: []string{"one"}},
+		},
+		{
+			name: "WithRequired missing",
+			file: "testdata/1.conf",
+			opts: []ff.Option{ff.WithEnvVarPrefix("TEST_PARSE"), ff.WithRequired("x", "s", "f")},
+			want: fftest.Vars{WantParseErrorString: `missing required flags "x", "f" (consulted commandline args, env vars with prefix "TEST_PARSE_", config file "testdata/1.conf")`},
+		},
+		{
+			name: "WithValidator errors are joined",
+			args: []string{"-i", "-1"},
+			opts: []ff.Option{
+				ff.WithValidator(func(fs ff.FlagSet) error {
+					if fs.Lookup("i").Value.String() == "-1" {
+						return errors.New("i must not be negative")
+					}
+					return nil
+				}),
+				ff.WithValidator(func(fs ff.FlagSet) error {
+					return errors.New("s must be set when i is given")
+				}),
+			},
+			want: fftest.Vars{WantParseErrorString: "i must not be negative\ns must be set when i is given"},
+		},
+		{
+			name: "WithValidator skipped when required flags are missing",
+			opts: []ff.Option{
+				ff.WithRequired("s"),
+				ff.WithValidator(func(fs ff.FlagSet) error {
+					return errors.New("validator called")
+				}),
+			},
+			want: fftest.Vars{WantParseErrorString: `missing required flags "s" (consulted commandline args)`},
+		},
 	} {
 		t.Run(testcase.name, func(t *testing.T) {
 			if testcase.file != "" {
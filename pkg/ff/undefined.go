@@ -0,0 +1,130 @@
+package ff
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// UndefinedFlagError is returned by the set function Parse hands to a
+// ConfigFileParser when the config file contains a key that is not defined in
+// the flag set.
+type UndefinedFlagError struct {
+	// Name is the offending config file key.
+	Name string
+	// Suggestion is the defined flag name closest to Name, if any is close
+	// enough to be a likely typo.
+	Suggestion string
+	// File is the config file the key was read from.
+	File string
+	// Line is the line of the key in File, or 0 if the parser does not track
+	// positions.
+	Line int
+}
+
+// Error implements the error interface.
+func (e *UndefinedFlagError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "config file flag %q not defined in flag set", e.Name)
+	switch {
+	case e.File != "" && e.Line > 0:
+		fmt.Fprintf(&b, " (%s:%d)", e.File, e.Line)
+	case e.File != "":
+		fmt.Fprintf(&b, " (%s)", e.File)
+	}
+	if e.Suggestion != "" {
+		fmt.Fprintf(&b, "; did you mean %q?", e.Suggestion)
+	}
+	return b.String()
+}
+
+// UndefinedFlagsError is returned by Parse when a config file contains one or
+// more keys that are not defined in the flag set.
+type UndefinedFlagsError []*UndefinedFlagError
+
+// Error implements the error interface.
+func (e UndefinedFlagsError) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap allows errors.Is and errors.As to inspect the individual
+// UndefinedFlagErrors.
+func (e UndefinedFlagsError) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// UndefinedFlagCollector lets a ConfigFileParser report every undefined key
+// of a config file, together with its line, instead of stopping at the first.
+// The zero value is ready to use.
+type UndefinedFlagCollector struct {
+	errs UndefinedFlagsError
+}
+
+// Set calls set with name and value. An UndefinedFlagError returned by set is
+// annotated with line and recorded, and Set returns nil so that parsing can
+// continue. Any other error is returned as is.
+func (c *UndefinedFlagCollector) Set(set func(name, value string) error, name, value string, line int) error {
+	err := set(name, value)
+	var undefinedErr *UndefinedFlagError
+	if errors.As(err, &undefinedErr) {
+		undefinedErr.Line = line
+		// Array values set the same key repeatedly; report it once.
+		if n := len(c.errs); n == 0 || c.errs[n-1].Name != name || c.errs[n-1].Line != line {
+			c.errs = append(c.errs, undefinedErr)
+		}
+		return nil
+	}
+	return err
+}
+
+// Err returns an UndefinedFlagsError for all recorded keys, or nil if there
+// were none.
+func (c *UndefinedFlagCollector) Err() error {
+	if len(c.errs) == 0 {
+		return nil
+	}
+	return c.errs
+}
+
+// suggestFlag returns the name in defined closest to name by edit distance, or
+// an empty string if none is close enough.
+func suggestFlag(name string, defined []string) string {
+	const maxDistance = 2
+
+	suggestion, best := "", maxDistance+1
+	for _, candidate := range defined {
+		if d := levenshtein(strings.ToLower(name), strings.ToLower(candidate)); d < best {
+			suggestion, best = candidate, d
+		}
+	}
+	return suggestion
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
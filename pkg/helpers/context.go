@@ -2,7 +2,6 @@ package helpers
 
 import (
 	"context"
-	"fmt"
 	"os"
 	"os/signal"
 	"sync"
@@ -11,21 +10,23 @@ import (
 )
 
 // WithCancelOnSignal returns a context that will get cancelled whenever one of
-// the specified signals is caught.
+// the specified signals is caught. The cause of the cancellation, see
+// context.Cause, is then a fabricator.InterruptedError.
 func WithCancelOnSignal(ctx context.Context, io fabricator.IOStreams, signals ...os.Signal) (context.Context, func()) {
 	var once sync.Once
-	ctx, cancel := context.WithCancel(ctx)
+	ctx, cancel := context.WithCancelCause(ctx)
 
-	ch := make(chan os.Signal)
+	ch := make(chan os.Signal, 1)
 
 	signal.Notify(ch, signals...)
 
 	go func() {
 		if sig := <-ch; sig != nil {
-			fmt.Fprintf(io.Out, "Context received signal: %s\n", sig)
+			io.Log().Warn("cancelling on signal", "signal", sig.String())
+			cancel(&fabricator.InterruptedError{Signal: sig})
 		}
 
-		cancel()
+		cancel(nil)
 	}()
 
 	return ctx, func() {
@@ -0,0 +1,84 @@
+package helpers
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// environ returns the environment of a command: base, without the variables
+// in unset, overlaid with env. Every variable occurs once, and the result is
+// sorted by name, so that it does not depend on the order of base or on how
+// the platform resolves duplicates.
+func environ(base []string, unset []string, env map[string]string) []string {
+	type variable struct{ name, value string }
+	vars := map[string]variable{}
+
+	for _, kv := range base {
+		name, value := splitEnv(kv)
+		if name == "" {
+			continue
+		}
+		vars[envKey(name)] = variable{name, value}
+	}
+	for _, name := range unset {
+		delete(vars, envKey(name))
+	}
+	for name, value := range env {
+		vars[envKey(name)] = variable{name, value}
+	}
+
+	keys := make([]string, 0, len(vars))
+	for key := range vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]string, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, vars[key].name+"="+vars[key].value)
+	}
+	return result
+}
+
+// filterEnv returns the variables of base whose name is in allowlist. A name
+// ending in "*" allows every variable with that prefix.
+func filterEnv(base []string, allowlist []string) []string {
+	var result []string
+	for _, kv := range base {
+		name, _ := splitEnv(kv)
+		for _, allowed := range allowlist {
+			if prefix, ok := strings.CutSuffix(allowed, "*"); ok && strings.HasPrefix(envKey(name), envKey(prefix)) ||
+				envKey(name) == envKey(allowed) {
+				result = append(result, kv)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// splitEnv splits a "name=value" environment entry. Windows keeps per-drive
+// working directories in entries like "=C:=C:\dir", so a leading "=" is part
+// of the name.
+func splitEnv(kv string) (name, value string) {
+	i := strings.Index(kv[min(1, len(kv)):], "=")
+	if i < 0 {
+		return kv, ""
+	}
+	i += min(1, len(kv))
+	return kv[:i], kv[i+1:]
+}
+
+// baseEnv returns the environment a command inherits from the current
+// process before the Executor's own variables are applied.
+func (e *Executor) baseEnv() []string {
+	switch {
+	case e.passthrough != nil:
+		return filterEnv(os.Environ(), e.passthrough)
+	case e.cleanEnv:
+		return nil
+	default:
+		return os.Environ()
+	}
+}
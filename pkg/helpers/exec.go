@@ -1,38 +1,73 @@
 package helpers
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"io"
 	"os/exec"
-	"runtime"
-	"strings"
+	"sync"
 	"time"
 
 	"code.cestus.io/tools/fabricator/pkg/fabricator"
 )
 
+// DefaultGracePeriod is the time an Executor gives an interrupted command to
+// exit before killing it.
+const DefaultGracePeriod = 5 * time.Second
+
+// DefaultStderrTail is the number of trailing stderr bytes an Executor keeps
+// of a failed command.
+const DefaultStderrTail = 8 * 1024
+
+// CommandExecutor runs external commands. It is implemented by Executor;
+// functions which only run commands can accept a CommandExecutor so that
+// callers are free to wrap or replace it.
+type CommandExecutor interface {
+	Run(ctx context.Context, path string, args ...string) error
+	Output(ctx context.Context, path string, args ...string) (string, error)
+	JSONOutput(ctx context.Context, target interface{}, path string, args ...string) error
+}
+
+var _ CommandExecutor = (*Executor)(nil)
+
+// Executor runs external commands. On Unix, commands are started in their own
+// process group, unless they read from a terminal, and when the context is
+// cancelled the whole group is interrupted, then killed once the grace period
+// is over, so that no orphaned children are left behind.
 type Executor struct {
-	root string
-	io   fabricator.IOStreams
-	env  fabricator.Environment
+	root        string
+	io          fabricator.IOStreams
+	env         fabricator.Environment
+	unsetEnv    []string
+	cleanEnv    bool
+	passthrough []string
+	gracePeriod time.Duration
+	timeout     time.Duration
+	retry       RetryPolicy
+	stderrTail  int
+	cmdRunner   Runner
+	stdin       io.Reader
+	prefix      string
+	lineFunc    LineFunc
 }
 
 func NewExecutor(root string, io fabricator.IOStreams) *Executor {
 	return &Executor{
-		root: root,
-		io:   io,
-		env:  make(fabricator.Environment),
+		root:        root,
+		io:          io,
+		env:         make(fabricator.Environment),
+		gracePeriod: DefaultGracePeriod,
+		stderrTail:  DefaultStderrTail,
 	}
 }
 
 func (e *Executor) WithRoot(root string) *Executor {
-	return &Executor{
-		root: root,
-		io:   e.io,
-		env:  e.env,
-	}
+	n := *e
+	n.root = root
+	return &n
 }
 
 func (e *Executor) WithEnv(key, value string) *Executor {
@@ -55,38 +90,144 @@ func (e *Executor) WithEnvMap(env fabricator.Environment) *Executor {
 		newEnv[k] = v
 	}
 
-	return &Executor{
-		root: e.root,
-		io:   e.io,
-		env:  newEnv,
-	}
+	n := *e
+	n.env = newEnv
+	return &n
 }
 
-func (e *Executor) setEnv(cmd *exec.Cmd) {
-	if e.env != nil {
-		env := append(os.Environ())
+// WithCleanEnv returns an Executor which runs commands with only the
+// variables set through WithEnv and WithEnvMap, instead of inheriting the
+// environment of the current process.
+func (e *Executor) WithCleanEnv() *Executor {
+	n := *e
+	n.cleanEnv = true
+	n.passthrough = nil
+	return &n
+}
 
-		for key, value := range e.env {
-			env = append(env, fmt.Sprintf("%s=%s", key, value))
-		}
+// WithEnvPassthrough returns an Executor which inherits only the listed
+// variables from the current process, in addition to those of previous calls.
+// A name ending in "*" passes through every variable with that prefix, e.g.
+// "GO*".
+func (e *Executor) WithEnvPassthrough(allowlist ...string) *Executor {
+	n := *e
+	n.cleanEnv = true
+	n.passthrough = append(append([]string{}, e.passthrough...), allowlist...)
+	return &n
+}
 
-		cmd.Env = env
+// WithoutEnv returns an Executor which removes keys from the environment of
+// commands, whether inherited or set through WithEnv. Variables set again
+// afterwards are kept.
+func (e *Executor) WithoutEnv(keys ...string) *Executor {
+	env := fabricator.Environment{}
+	for k, v := range e.env {
+		env[k] = v
+	}
+	for _, key := range keys {
+		for k := range env {
+			if envKey(k) == envKey(key) {
+				delete(env, k)
+			}
+		}
 	}
+
+	n := *e
+	n.env = env
+	n.unsetEnv = append(append([]string{}, e.unsetEnv...), keys...)
+	return &n
 }
 
-func (e *Executor) Output(ctx context.Context, path string, args ...string) (string, error) {
-	cmd := exec.CommandContext(ctx, path, args...)
+// WithRunner returns an Executor which hands its commands to r instead of the
+// Runner on the context.
+func (e *Executor) WithRunner(r Runner) *Executor {
+	n := *e
+	n.cmdRunner = r
+	return &n
+}
+
+// WithStdin returns an Executor which feeds r to the standard input of
+// commands. Without it, Run connects IOStreams.In and Output connects nothing.
+// If the Executor retries commands, r is read completely before the first
+// attempt.
+func (e *Executor) WithStdin(r io.Reader) *Executor {
+	n := *e
+	n.stdin = r
+	return &n
+}
+
+// WithPrefix returns an Executor which writes prefix, e.g. "[protoc] ", in
+// front of every line a command writes to IOStreams.Out and IOStreams.ErrOut.
+// The stdout returned by Output is not prefixed.
+func (e *Executor) WithPrefix(prefix string) *Executor {
+	n := *e
+	n.prefix = prefix
+	return &n
+}
+
+// WithLineFunc returns an Executor which calls f with every line commands
+// write to stdout or stderr, as soon as it is complete. The output is still
+// written to IOStreams.Out and IOStreams.ErrOut, and returned by Output.
+func (e *Executor) WithLineFunc(f LineFunc) *Executor {
+	n := *e
+	n.lineFunc = f
+	return &n
+}
+
+// WithGracePeriod returns an Executor which waits d after interrupting a
+// cancelled command before killing it.
+func (e *Executor) WithGracePeriod(d time.Duration) *Executor {
+	n := *e
+	n.gracePeriod = d
+	return &n
+}
+
+// WithStderrTail returns an Executor which keeps the last size bytes of the
+// stderr of failed commands in the returned ExecError.
+func (e *Executor) WithStderrTail(size int) *Executor {
+	n := *e
+	n.stderrTail = size
+	return &n
+}
+
+// WithTimeout returns an Executor which cancels every attempt of a command
+// that runs longer than d. Zero means no timeout.
+func (e *Executor) WithTimeout(d time.Duration) *Executor {
+	n := *e
+	n.timeout = d
+	return &n
+}
+
+// WithRetry returns an Executor which retries failed commands according to
+// policy.
+func (e *Executor) WithRetry(policy RetryPolicy) *Executor {
+	n := *e
+	n.retry = policy
+	return &n
+}
+
+func (e *Executor) command(path string, args ...string) *exec.Cmd {
+	cmd := exec.Command(path, args...)
 	cmd.Dir = e.root
-	cmd.Stderr = e.io.ErrOut
 	e.setEnv(cmd)
+	return cmd
+}
+
+// setEnv sets the environment of cmd. Each variable occurs once and they are
+// sorted by name, so commands see the same environment on every run.
+func (e *Executor) setEnv(cmd *exec.Cmd) {
+	cmd.Env = environ(e.baseEnv(), e.unsetEnv, e.env)
+}
 
-	data, err := cmd.Output()
+func (e *Executor) Output(ctx context.Context, path string, args ...string) (string, error) {
+	var stdout bytes.Buffer
 
+	err := e.attempt(ctx, path, args, &stdout)
 	if err != nil {
 		return "", err
 	}
 
-	return string(data), nil
+	return stdout.String(), nil
 }
 
 func (e *Executor) JSONOutput(ctx context.Context, target interface{}, path string, args ...string) error {
@@ -104,35 +245,124 @@ func (e *Executor) JSONOutput(ctx context.Context, target interface{}, path stri
 }
 
 func (e *Executor) Run(ctx context.Context, path string, args ...string) error {
-	if e.io.Out != nil {
-		fmt.Fprintf(e.io.Out, "executing %s %s\n", path, strings.Join(args, " "))
+	e.io.Log().Info("executing", "path", path, "args", args)
+
+	return e.attempt(ctx, path, args, nil)
+}
+
+// connect connects the output of cmd: stdout to capture, or to IOStreams.Out
+// if capture is nil, and stderr to IOStreams.ErrOut and tail. Output streamed
+// to IOStreams is prefixed, and every line passed to the LineFunc. The returned
+// function writes out a final line without line ending once cmd has exited.
+func (e *Executor) connect(cmd *exec.Cmd, capture *bytes.Buffer, tail io.Writer) (flush func() error) {
+	var mu sync.Mutex
+	var lineWriters []*lineWriter
+	lines := func(stream Stream, w io.Writer) io.Writer {
+		if e.prefix == "" && e.lineFunc == nil {
+			return w
+		}
+		lw := &lineWriter{mu: &mu, stream: stream, w: w, prefix: e.prefix, fn: e.lineFunc}
+		lineWriters = append(lineWriters, lw)
+		return lw
 	}
 
-	cmd := exec.Command(path, args...)
-	cmd.Dir = e.root
-	cmd.Stdout = e.io.Out
-	cmd.Stderr = e.io.ErrOut
-	e.setEnv(cmd)
+	switch {
+	case capture != nil && e.lineFunc != nil:
+		cmd.Stdout = io.MultiWriter(capture, lines(Stdout, nil))
+	case capture != nil:
+		cmd.Stdout = capture
+	case e.io.Out != nil || e.lineFunc != nil:
+		cmd.Stdout = lines(Stdout, e.io.Out)
+	}
+
+	cmd.Stderr = tail
+	if e.io.ErrOut != nil || e.lineFunc != nil {
+		cmd.Stderr = io.MultiWriter(lines(Stderr, e.io.ErrOut), tail)
+	}
+
+	return func() error {
+		for _, lw := range lineWriters {
+			if err := lw.Flush(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
 
-	go func() {
-		<-ctx.Done()
-		// Do not send signals on a terminated process
-		if cmd.Process == nil {
-			return
+// attempt runs the command until it succeeds or the retry policy gives up.
+// stdout of the last attempt is captured in capture, or streamed to
+// IOStreams.Out if capture is nil; stderr is streamed to IOStreams.ErrOut and
+// its tail kept for the returned ExecError. Every attempt is subject to the
+// timeout.
+func (e *Executor) attempt(ctx context.Context, path string, args []string, capture *bytes.Buffer) error {
+	stdin := e.stdin
+	if stdin == nil && capture == nil {
+		stdin = e.io.In
+	}
+	var stdinData []byte
+	if e.stdin != nil && e.retry.MaxAttempts > 1 {
+		var err error
+		if stdinData, err = io.ReadAll(e.stdin); err != nil {
+			return fmt.Errorf("reading stdin for %s: %w", (&ExecError{Path: path, Args: args}).CommandLine(), err)
 		}
+	}
 
-		if runtime.GOOS == "windows" {
-			_ = cmd.Process.Signal(os.Kill)
-			return
+	for n := 1; ; n++ {
+		stderr := newTailBuffer(e.stderrTail)
+		cmd := e.command(path, args...)
+		cmd.Stdin = stdin
+		if stdinData != nil {
+			cmd.Stdin = bytes.NewReader(stdinData)
+		}
+		if capture != nil {
+			capture.Reset()
 		}
+		flush := e.connect(cmd, capture, stderr)
 
-		go func() {
-			time.Sleep(5 * time.Second)
-			_ = cmd.Process.Signal(os.Kill)
-		}()
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if e.timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, e.timeout)
+		}
+		start := time.Now()
+		err := e.runner(ctx).RunCommand(attemptCtx, cmd)
+		if flushErr := flush(); err == nil {
+			err = flushErr
+		}
+		if err != nil {
+			execErr := &ExecError{
+				Path:     path,
+				Args:     args,
+				Dir:      e.root,
+				ExitCode: -1,
+				Duration: time.Since(start),
+				Stderr:   stderr.String(),
+				Err:      err,
+			}
+			var exitErr exitCoder
+			if cmd.ProcessState != nil {
+				execErr.ExitCode = cmd.ProcessState.ExitCode()
+				execErr.Signal = exitSignal(cmd.ProcessState)
+			} else if errors.As(err, &exitErr) {
+				execErr.ExitCode = exitErr.ExitCode()
+			}
+			if ctx.Err() == nil && attemptCtx.Err() == context.DeadlineExceeded {
+				execErr.Timeout = e.timeout
+			}
+			err = execErr
+		}
+		cancel()
 
-		cmd.Process.Signal(os.Interrupt)
-	}()
+		if err == nil || ctx.Err() != nil || !e.retry.shouldRetry(n, err, stderr.String()) {
+			return err
+		}
 
-	return cmd.Run()
+		timer := time.NewTimer(e.retry.backoff(n))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
 }
@@ -0,0 +1,39 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package helpers
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// waitExitedSupported reports whether waitExited works on this platform.
+const waitExitedSupported = true
+
+// waitExited waits for p to exit without reaping it, so that neither its
+// process ID nor the ID of the process group it leads are reused before
+// exec.Cmd.Wait.
+func waitExited(p *os.Process) error {
+	kq, err := unix.Kqueue()
+	if err != nil {
+		return err
+	}
+	defer unix.Close(kq)
+
+	changes := make([]unix.Kevent_t, 1)
+	unix.SetKevent(&changes[0], p.Pid, unix.EVFILT_PROC, unix.EV_ADD|unix.EV_ONESHOT)
+	changes[0].Fflags = unix.NOTE_EXIT
+	events := make([]unix.Kevent_t, 1)
+	for {
+		_, err := unix.Kevent(kq, changes, events, nil)
+		switch err {
+		case unix.EINTR:
+			continue
+		case unix.ESRCH:
+			// p exited before it could be watched.
+			return nil
+		}
+		return err
+	}
+}
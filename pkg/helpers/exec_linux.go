@@ -0,0 +1,25 @@
+//go:build linux
+
+package helpers
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// waitExitedSupported reports whether waitExited works on this platform.
+const waitExitedSupported = true
+
+// waitExited waits for p to exit without reaping it, so that neither its
+// process ID nor the ID of the process group it leads are reused before
+// exec.Cmd.Wait.
+func waitExited(p *os.Process) error {
+	var info unix.Siginfo
+	for {
+		err := unix.Waitid(unix.P_PID, p.Pid, &info, unix.WEXITED|unix.WNOWAIT, nil)
+		if err != unix.EINTR {
+			return err
+		}
+	}
+}
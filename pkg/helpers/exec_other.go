@@ -0,0 +1,16 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package helpers
+
+import (
+	"errors"
+	"os"
+)
+
+// waitExitedSupported reports whether waitExited works on this platform.
+const waitExitedSupported = false
+
+// waitExited is not supported on this platform.
+func waitExited(p *os.Process) error {
+	return errors.ErrUnsupported
+}
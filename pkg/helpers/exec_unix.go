@@ -0,0 +1,71 @@
+//go:build !windows
+
+package helpers
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// setProcessGroup makes cmd the leader of a new process group, so that it and
+// all its children can be signalled at once, and reports whether it did.
+// Commands reading from a terminal are left in the process group of
+// fabricator, which the terminal keeps in the foreground; in a group of
+// their own, they would be stopped by SIGTTIN as soon as they read from it.
+func setProcessGroup(cmd *exec.Cmd) bool {
+	// The group is only signalled while its leader is not reaped, which
+	// takes waitExited.
+	if !waitExitedSupported {
+		return false
+	}
+	if f, ok := cmd.Stdin.(*os.File); ok && isTerminal(f) {
+		return false
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+	return true
+}
+
+// isTerminal reports whether f is a terminal, which has a foreground process
+// group.
+func isTerminal(f *os.File) bool {
+	_, err := unix.IoctlGetInt(int(f.Fd()), unix.TIOCGPGRP)
+	return err == nil
+}
+
+// exitSignal returns the signal which terminated the process, if any.
+func exitSignal(state *os.ProcessState) os.Signal {
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return status.Signal()
+	}
+	return nil
+}
+
+// interruptProcessGroup interrupts the process group p leads, or only p if
+// group is false.
+func interruptProcessGroup(p *os.Process, group bool) error {
+	if !group {
+		return p.Signal(os.Interrupt)
+	}
+	return syscall.Kill(-p.Pid, syscall.SIGINT)
+}
+
+// killProcessGroup kills the process group p leads, or only p if group is
+// false.
+func killProcessGroup(p *os.Process, group bool) error {
+	if !group {
+		return p.Kill()
+	}
+	return syscall.Kill(-p.Pid, syscall.SIGKILL)
+}
+
+// envKey returns the key under which name is deduplicated in an environment.
+// Environment variable names are case-sensitive on Unix.
+func envKey(name string) string {
+	return name
+}
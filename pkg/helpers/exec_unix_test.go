@@ -0,0 +1,375 @@
+//go:build !windows
+
+package helpers_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"code.cestus.io/tools/fabricator/pkg/fabricator"
+	"code.cestus.io/tools/fabricator/pkg/helpers"
+)
+
+func TestExecutorCancelSignalsProcessGroup(t *testing.T) {
+	// The background loop stands in for a grandchild like protoc; background
+	// jobs of non-interactive shells ignore SIGINT, so only the kill of the
+	// process group after the grace period ends it.
+	const script = `(while true; do echo tick >> "$TICKS"; sleep 0.02; done) & wait`
+
+	for _, testcase := range []struct {
+		name string
+		run  func(ctx context.Context, e *helpers.Executor) error
+	}{
+		{
+			name: "Run",
+			run: func(ctx context.Context, e *helpers.Executor) error {
+				return e.Run(ctx, "sh", "-c", script)
+			},
+		},
+		{
+			name: "Output",
+			run: func(ctx context.Context, e *helpers.Executor) error {
+				_, err := e.Output(ctx, "sh", "-c", script)
+				return err
+			},
+		},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			ticks := filepath.Join(t.TempDir(), "ticks")
+			executor := helpers.NewExecutor("", fabricator.NewTestIOStreamsDiscard()).
+				WithEnv("TICKS", ticks).
+				WithGracePeriod(100 * time.Millisecond)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go func() {
+				for {
+					if _, err := os.Stat(ticks); err == nil {
+						cancel()
+						return
+					}
+					time.Sleep(10 * time.Millisecond)
+				}
+			}()
+
+			start := time.Now()
+			if err := testcase.run(ctx, executor); err == nil {
+				t.Fatal("want error for cancelled command, have none")
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Fatalf("want cancelled command to return quickly, took %s", elapsed)
+			}
+
+			// Give the kill time to land, then make sure nothing writes anymore.
+			time.Sleep(200 * time.Millisecond)
+			before, err := os.Stat(ticks)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			time.Sleep(200 * time.Millisecond)
+			after, err := os.Stat(ticks)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if after.Size() != before.Size() {
+				t.Fatal("background process survived cancellation")
+			}
+		})
+	}
+}
+
+func TestExecutorRetry(t *testing.T) {
+	// Fails with "locked" on stderr until the attempt given as $1 is reached.
+	const script = `n=$(cat "$COUNT" 2>/dev/null || echo 0); n=$((n+1)); echo $n > "$COUNT"; [ $n -ge $1 ] && exit 0; echo locked >&2; exit 3`
+
+	for _, testcase := range []struct {
+		name         string
+		policy       helpers.RetryPolicy
+		succeedAt    string
+		wantErr      bool
+		wantAttempts string
+	}{
+		{
+			name:         "no policy runs once",
+			succeedAt:    "2",
+			wantErr:      true,
+			wantAttempts: "1",
+		},
+		{
+			name:         "retries until success",
+			policy:       helpers.RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Millisecond, Jitter: 0.5},
+			succeedAt:    "3",
+			wantAttempts: "3",
+		},
+		{
+			name:         "gives up after max attempts",
+			policy:       helpers.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond},
+			succeedAt:    "3",
+			wantErr:      true,
+			wantAttempts: "2",
+		},
+		{
+			name: "predicate over exit code and stderr",
+			policy: helpers.RetryPolicy{MaxAttempts: 5, Retryable: func(exitCode int, stderr string) bool {
+				return exitCode == 3 && strings.Contains(stderr, "locked")
+			}},
+			succeedAt:    "2",
+			wantAttempts: "2",
+		},
+		{
+			name: "predicate rejecting the failure",
+			policy: helpers.RetryPolicy{MaxAttempts: 5, Retryable: func(exitCode int, stderr string) bool {
+				return exitCode == 1
+			}},
+			succeedAt:    "2",
+			wantErr:      true,
+			wantAttempts: "1",
+		},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			count := filepath.Join(t.TempDir(), "count")
+			executor := helpers.NewExecutor("", fabricator.NewTestIOStreamsDiscard()).
+				WithEnv("COUNT", count).
+				WithRetry(testcase.policy)
+
+			_, err := executor.Output(context.Background(), "sh", "-c", script, "sh", testcase.succeedAt)
+			if testcase.wantErr != (err != nil) {
+				t.Fatalf("want error %v, have %v", testcase.wantErr, err)
+			}
+			data, err := os.ReadFile(count)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if attempts := strings.TrimSpace(string(data)); attempts != testcase.wantAttempts {
+				t.Fatalf("want %s attempts, have %s", testcase.wantAttempts, attempts)
+			}
+		})
+	}
+}
+
+func TestExecutorTimeout(t *testing.T) {
+	executor := helpers.NewExecutor("", fabricator.NewTestIOStreamsDiscard()).
+		WithTimeout(100 * time.Millisecond).
+		WithGracePeriod(100 * time.Millisecond)
+
+	start := time.Now()
+	err := executor.Run(context.Background(), "sleep", "5")
+	if err == nil || !strings.Contains(err.Error(), "timed out after 100ms") {
+		t.Fatalf("want timeout error, have %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("want timed out command to return quickly, took %s", elapsed)
+	}
+}
+
+func TestExecutorExecError(t *testing.T) {
+	io, _, _, errOut := fabricator.NewTestIOStreams()
+	executor := helpers.NewExecutor(t.TempDir(), io).WithStderrTail(len("go: lock held\n"))
+
+	_, err := executor.Output(context.Background(), "sh", "-c", `echo "some noise" >&2; echo "go: lock held" >&2; exit 2`)
+
+	var execErr *helpers.ExecError
+	if !errors.As(err, &execErr) {
+		t.Fatalf("want *helpers.ExecError, have %T (%v)", err, err)
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("want wrapped *exec.ExitError, have %v", execErr.Err)
+	}
+	if execErr.ExitCode != 2 || execErr.Signal != nil {
+		t.Fatalf("want exit code 2 without signal, have %d, %v", execErr.ExitCode, execErr.Signal)
+	}
+	if execErr.Stderr != "go: lock held\n" {
+		t.Fatalf("want stderr tail %q, have %q", "go: lock held\n", execErr.Stderr)
+	}
+	if !strings.Contains(errOut.String(), "some noise") {
+		t.Fatalf("want stderr streamed to ErrOut, have %q", errOut.String())
+	}
+	want := `command sh -c "echo \"some noise\" >&2; echo \"go: lock held\" >&2; exit 2" in ` + execErr.Dir + " failed after "
+	if !strings.HasPrefix(err.Error(), want) || !strings.HasSuffix(err.Error(), ": exit status 2: go: lock held") {
+		t.Fatalf("unexpected error message %q", err.Error())
+	}
+
+	err = executor.Run(context.Background(), "sh", "-c", `kill -TERM $$`)
+	if !errors.As(err, &execErr) || execErr.Signal != syscall.SIGTERM || execErr.ExitCode != -1 {
+		t.Fatalf("want ExecError for SIGTERM, have %#v", err)
+	}
+}
+
+func TestExecutorEnv(t *testing.T) {
+	t.Setenv("FABRICATOR_TEST_A", "inherited")
+	t.Setenv("FABRICATOR_TEST_B", "inherited")
+
+	for _, testcase := range []struct {
+		name     string
+		executor func(e *helpers.Executor) *helpers.Executor
+		want     []string
+	}{
+		{
+			name: "inherited",
+			executor: func(e *helpers.Executor) *helpers.Executor {
+				return e.WithEnv("FABRICATOR_TEST_B", "set")
+			},
+			want: []string{"FABRICATOR_TEST_A=inherited", "FABRICATOR_TEST_B=set"},
+		},
+		{
+			name: "without",
+			executor: func(e *helpers.Executor) *helpers.Executor {
+				return e.WithEnv("FABRICATOR_TEST_C", "set").WithoutEnv("FABRICATOR_TEST_A", "FABRICATOR_TEST_C")
+			},
+			want: []string{"FABRICATOR_TEST_B=inherited"},
+		},
+		{
+			name: "set after without",
+			executor: func(e *helpers.Executor) *helpers.Executor {
+				return e.WithoutEnv("FABRICATOR_TEST_A").WithEnv("FABRICATOR_TEST_A", "set")
+			},
+			want: []string{"FABRICATOR_TEST_A=set", "FABRICATOR_TEST_B=inherited"},
+		},
+		{
+			name: "clean",
+			executor: func(e *helpers.Executor) *helpers.Executor {
+				return e.WithCleanEnv().WithEnv("FABRICATOR_TEST_C", "set")
+			},
+			want: []string{"FABRICATOR_TEST_C=set"},
+		},
+		{
+			name: "passthrough",
+			executor: func(e *helpers.Executor) *helpers.Executor {
+				return e.WithEnvPassthrough("FABRICATOR_TEST_B").WithEnvPassthrough("FABRICATOR_TEST_A")
+			},
+			want: []string{"FABRICATOR_TEST_A=inherited", "FABRICATOR_TEST_B=inherited"},
+		},
+		{
+			name: "passthrough prefix",
+			executor: func(e *helpers.Executor) *helpers.Executor {
+				return e.WithEnvPassthrough("FABRICATOR_TEST_*").WithoutEnv("FABRICATOR_TEST_A")
+			},
+			want: []string{"FABRICATOR_TEST_B=inherited"},
+		},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			executor := testcase.executor(helpers.NewExecutor("", fabricator.NewTestIOStreamsDiscard()))
+			out, err := executor.Output(context.Background(), "env")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, kv := range strings.Split(strings.TrimSpace(out), "\n") {
+				if strings.HasPrefix(kv, "FABRICATOR_TEST_") {
+					got = append(got, kv)
+				}
+			}
+			if strings.Join(got, "\n") != strings.Join(testcase.want, "\n") {
+				t.Errorf("want environment %q, have %q", testcase.want, got)
+			}
+		})
+	}
+}
+
+func TestExecutorStdin(t *testing.T) {
+	t.Run("Output", func(t *testing.T) {
+		out, err := helpers.NewExecutor("", fabricator.NewTestIOStreamsDiscard()).
+			WithStdin(strings.NewReader("syntax = \"proto3\";\n")).
+			Output(context.Background(), "cat")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if out != "syntax = \"proto3\";\n" {
+			t.Errorf("want stdin echoed, have %q", out)
+		}
+	})
+
+	t.Run("Run connects IOStreams.In", func(t *testing.T) {
+		io, in, out, _ := fabricator.NewTestIOStreams()
+		in.WriteString("hello\n")
+		if err := helpers.NewExecutor("", io).Run(context.Background(), "cat"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if out.String() != "hello\n" {
+			t.Errorf("want stdin echoed, have %q", out)
+		}
+	})
+
+	t.Run("retried", func(t *testing.T) {
+		// Consumes stdin, then fails on the first attempt only.
+		const script = `cat; [ -e "$COUNT" ] && exit 0; touch "$COUNT"; exit 1`
+		out, err := helpers.NewExecutor("", fabricator.NewTestIOStreamsDiscard()).
+			WithEnv("COUNT", filepath.Join(t.TempDir(), "count")).
+			WithRetry(helpers.RetryPolicy{MaxAttempts: 2}).
+			WithStdin(strings.NewReader("input\n")).
+			Output(context.Background(), "sh", "-c", script)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if out != "input\n" {
+			t.Errorf("want stdin replayed on retry, have %q", out)
+		}
+	})
+}
+
+func TestExecutorLines(t *testing.T) {
+	const script = `echo one; echo two >&2; printf three`
+
+	var stdout, stderr []string
+	lineFunc := func(stream helpers.Stream, line string) {
+		switch stream {
+		case helpers.Stdout:
+			stdout = append(stdout, line)
+		case helpers.Stderr:
+			stderr = append(stderr, line)
+		}
+	}
+
+	t.Run("Run", func(t *testing.T) {
+		stdout, stderr = nil, nil
+		io, _, out, errOut := fabricator.NewTestIOStreams()
+		err := helpers.NewExecutor("", io).
+			WithPrefix("[component] ").
+			WithLineFunc(lineFunc).
+			Run(context.Background(), "sh", "-c", script)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if out.String() != "[component] one\n[component] three" {
+			t.Errorf("want prefixed stdout, have %q", out)
+		}
+		if errOut.String() != "[component] two\n" {
+			t.Errorf("want prefixed stderr, have %q", errOut)
+		}
+		if strings.Join(stdout, "|") != "one|three" || strings.Join(stderr, "|") != "two" {
+			t.Errorf("want lines one|three and two, have %q and %q", stdout, stderr)
+		}
+	})
+
+	t.Run("Output", func(t *testing.T) {
+		stdout, stderr = nil, nil
+		io, _, _, errOut := fabricator.NewTestIOStreams()
+		out, err := helpers.NewExecutor("", io).
+			WithPrefix("[component] ").
+			WithLineFunc(lineFunc).
+			Output(context.Background(), "sh", "-c", script)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if out != "one\nthree" {
+			t.Errorf("want unprefixed output, have %q", out)
+		}
+		if errOut.String() != "[component] two\n" {
+			t.Errorf("want prefixed stderr, have %q", errOut)
+		}
+		if strings.Join(stdout, "|") != "one|three" || strings.Join(stderr, "|") != "two" {
+			t.Errorf("want lines one|three and two, have %q and %q", stdout, stderr)
+		}
+	})
+}
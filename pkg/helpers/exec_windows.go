@@ -0,0 +1,35 @@
+//go:build windows
+
+package helpers
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// setProcessGroup is a no-op on Windows, which has no process groups that can
+// be signalled, and returns false.
+func setProcessGroup(cmd *exec.Cmd) bool { return false }
+
+// exitSignal returns nil, since processes on Windows are not terminated by
+// signals.
+func exitSignal(state *os.ProcessState) os.Signal {
+	return nil
+}
+
+// interruptProcessGroup kills the process, since Windows cannot deliver
+// os.Interrupt to another process.
+func interruptProcessGroup(p *os.Process, group bool) error {
+	return p.Kill()
+}
+
+func killProcessGroup(p *os.Process, group bool) error {
+	return p.Kill()
+}
+
+// envKey returns the key under which name is deduplicated in an environment.
+// Environment variable names are case-insensitive on Windows.
+func envKey(name string) string {
+	return strings.ToUpper(name)
+}
@@ -0,0 +1,80 @@
+package helpers
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExecError is returned by Executor when a command cannot be started or exits
+// unsuccessfully. The underlying error, usually an *exec.ExitError, is
+// available through errors.As and errors.Unwrap.
+type ExecError struct {
+	// Path and Args are the command as passed to the Executor.
+	Path string
+	Args []string
+	// Dir is the working directory of the command; empty means the current one.
+	Dir string
+	// ExitCode is the exit code of the command, or -1 if it did not exit
+	// normally or was never started.
+	ExitCode int
+	// Signal is the signal which terminated the command, if any.
+	Signal os.Signal
+	// Duration is the time the command ran.
+	Duration time.Duration
+	// Timeout is set if the command was stopped for exceeding the timeout of
+	// the Executor.
+	Timeout time.Duration
+	// Stderr is the tail of the command's stderr, also streamed to
+	// IOStreams.ErrOut while it ran.
+	Stderr string
+	Err    error
+}
+
+// Error implements the error interface. It includes the last line of stderr.
+func (e *ExecError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "command %s", e.CommandLine())
+	if e.Dir != "" {
+		fmt.Fprintf(&b, " in %s", e.Dir)
+	}
+	switch {
+	case e.Timeout > 0:
+		fmt.Fprintf(&b, " timed out after %s", e.Timeout)
+	default:
+		fmt.Fprintf(&b, " failed after %s", e.Duration.Round(time.Millisecond))
+	}
+	fmt.Fprintf(&b, ": %v", e.Err)
+	if line := lastLine(e.Stderr); line != "" {
+		fmt.Fprintf(&b, ": %s", line)
+	}
+	return b.String()
+}
+
+// Unwrap returns the underlying error.
+func (e *ExecError) Unwrap() error {
+	return e.Err
+}
+
+// CommandLine returns the command with its arguments, quoted where needed to
+// be pasted into a shell.
+func (e *ExecError) CommandLine() string {
+	parts := make([]string, 0, len(e.Args)+1)
+	for _, part := range append([]string{e.Path}, e.Args...) {
+		if part == "" || strings.ContainsAny(part, " \t\n\"'\\$`") {
+			part = strconv.Quote(part)
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
+
+func lastLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		s = s[i+1:]
+	}
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,219 @@
+package helpers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Invocation is a command as handed to a Runner.
+type Invocation struct {
+	// Path and Args are the command as passed to the Executor.
+	Path string
+	Args []string
+	// Dir is the working directory; empty means the current one.
+	Dir string
+	// Env is the complete environment of the command, as "key=value" pairs.
+	Env []string
+	// Stdin is everything the command was given on its standard input, if
+	// that was a reader which is known to end: a bytes.Reader, bytes.Buffer
+	// or strings.Reader.
+	Stdin []byte
+	// StdinConnected is true if the command was given a standard input,
+	// whether it was recorded in Stdin or not.
+	StdinConnected bool
+}
+
+// Getenv returns the value of the environment variable key of the command.
+func (i Invocation) Getenv(key string) string {
+	for _, kv := range slices.Backward(i.Env) {
+		if name, value := splitEnv(kv); envKey(name) == envKey(key) {
+			return value
+		}
+	}
+	return ""
+}
+
+// String returns the command line of the invocation.
+func (i Invocation) String() string {
+	return (&ExecError{Path: i.Path, Args: i.Args}).CommandLine()
+}
+
+// RecordingRunner is a Runner which records every command before handing it
+// to Runner, or to the operating system if Runner is nil.
+type RecordingRunner struct {
+	Runner Runner
+
+	mu          sync.Mutex
+	invocations []Invocation
+}
+
+// RunCommand implements Runner.
+func (r *RecordingRunner) RunCommand(ctx context.Context, cmd *exec.Cmd) error {
+	invocation, err := record(cmd)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.invocations = append(r.invocations, invocation)
+	r.mu.Unlock()
+
+	if r.Runner != nil {
+		return r.Runner.RunCommand(ctx, cmd)
+	}
+	return processRunner{gracePeriod: DefaultGracePeriod}.RunCommand(ctx, cmd)
+}
+
+// Invocations returns the commands recorded so far, in the order they ran.
+func (r *RecordingRunner) Invocations() []Invocation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.invocations)
+}
+
+// record returns the invocation of cmd. Stdin is read completely and replaced
+// by a reader over the same bytes if it is known to end; other readers, like
+// os.Stdin, may block forever and are left to the command.
+func record(cmd *exec.Cmd) (Invocation, error) {
+	// exec.Command resolves the path; the first argument keeps it as given.
+	invocation := Invocation{
+		Path: cmd.Args[0],
+		Args: slices.Clone(cmd.Args[1:]),
+		Dir:  cmd.Dir,
+		Env:  slices.Clone(cmd.Env),
+	}
+	switch r := cmd.Stdin.(type) {
+	case nil:
+	case *bytes.Reader, *bytes.Buffer, *strings.Reader:
+		stdin, err := io.ReadAll(r)
+		if err != nil {
+			return invocation, fmt.Errorf("reading stdin of %s: %w", invocation, err)
+		}
+		invocation.Stdin = stdin
+		invocation.StdinConnected = true
+		cmd.Stdin = bytes.NewReader(stdin)
+	default:
+		invocation.StdinConnected = true
+	}
+	return invocation, nil
+}
+
+// FakeCommand is the scripted result of a command run by a FakeRunner.
+type FakeCommand struct {
+	// Stdout and Stderr are written to the standard output and error of the
+	// command.
+	Stdout string
+	Stderr string
+	// ExitCode is the exit code of the command. The command fails with a
+	// FakeExitError unless it is 0.
+	ExitCode int
+}
+
+// FakeExitError is returned by a FakeRunner for a FakeCommand with a non-zero
+// exit code.
+type FakeExitError struct {
+	Code int
+}
+
+// Error implements the error interface.
+func (e *FakeExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// ExitCode returns the scripted exit code.
+func (e *FakeExitError) ExitCode() int {
+	return e.Code
+}
+
+// FakeRunner is a Runner which records commands like RecordingRunner but,
+// instead of running them, replays the FakeCommand scripted for their command
+// line. Commands without a script fail.
+type FakeRunner struct {
+	RecordingRunner
+
+	mu      sync.Mutex
+	scripts map[string][]FakeCommand
+}
+
+// NewFakeRunner returns a FakeRunner without scripts.
+func NewFakeRunner() *FakeRunner {
+	r := &FakeRunner{scripts: map[string][]FakeCommand{}}
+	r.RecordingRunner.Runner = runnerFunc(r.replay)
+	return r
+}
+
+// Script makes the runner reply to the given command line with results, one
+// per run, the last of which is repeated. path is matched against the path
+// passed to the Executor, e.g. "go".
+func (r *FakeRunner) Script(results []FakeCommand, path string, args ...string) *FakeRunner {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := Invocation{Path: path, Args: args}.String()
+	r.scripts[key] = append(r.scripts[key], results...)
+	return r
+}
+
+// Reply makes the runner reply to the given command line with stdout and exit
+// code 0.
+func (r *FakeRunner) Reply(stdout string, path string, args ...string) *FakeRunner {
+	return r.Script([]FakeCommand{{Stdout: stdout}}, path, args...)
+}
+
+// Fail makes the runner reply to the given command line with stderr and
+// exitCode.
+func (r *FakeRunner) Fail(exitCode int, stderr string, path string, args ...string) *FakeRunner {
+	return r.Script([]FakeCommand{{Stderr: stderr, ExitCode: exitCode}}, path, args...)
+}
+
+func (r *FakeRunner) replay(ctx context.Context, cmd *exec.Cmd) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	key := Invocation{Path: cmd.Args[0], Args: cmd.Args[1:]}.String()
+
+	r.mu.Lock()
+	results, ok := r.scripts[key]
+	var result FakeCommand
+	if ok {
+		result = results[0]
+		if len(results) > 1 {
+			r.scripts[key] = results[1:]
+		}
+	}
+	r.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("fake runner: no script for command %s", key)
+	}
+
+	for _, out := range []struct {
+		w    io.Writer
+		data string
+	}{{cmd.Stdout, result.Stdout}, {cmd.Stderr, result.Stderr}} {
+		if out.w != nil && out.data != "" {
+			if _, err := io.Copy(out.w, strings.NewReader(out.data)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if result.ExitCode != 0 {
+		return &FakeExitError{Code: result.ExitCode}
+	}
+	return nil
+}
+
+// runnerFunc adapts a function to the Runner interface.
+type runnerFunc func(ctx context.Context, cmd *exec.Cmd) error
+
+// RunCommand implements Runner.
+func (f runnerFunc) RunCommand(ctx context.Context, cmd *exec.Cmd) error {
+	return f(ctx, cmd)
+}
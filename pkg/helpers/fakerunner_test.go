@@ -0,0 +1,90 @@
+package helpers_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"code.cestus.io/tools/fabricator/pkg/fabricator"
+	"code.cestus.io/tools/fabricator/pkg/helpers"
+)
+
+func TestFakeRunnerStdin(t *testing.T) {
+	// A pipe which is never closed stands in for os.Stdin.
+	pipe, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pipe.Close()
+	defer w.Close()
+
+	tests := []struct {
+		name          string
+		executor      func(io fabricator.IOStreams) *helpers.Executor
+		expectStdin   string
+		expectConnect bool
+	}{
+		{
+			name: "finite readers are recorded",
+			executor: func(io fabricator.IOStreams) *helpers.Executor {
+				return helpers.NewExecutor("", io).WithStdin(strings.NewReader("input"))
+			},
+			expectStdin:   "input",
+			expectConnect: true,
+		},
+		{
+			name: "IOStreams.In is recorded",
+			executor: func(io fabricator.IOStreams) *helpers.Executor {
+				io.In = bytes.NewBufferString("input")
+				return helpers.NewExecutor("", io)
+			},
+			expectStdin:   "input",
+			expectConnect: true,
+		},
+		{
+			name: "other readers are not read",
+			executor: func(io fabricator.IOStreams) *helpers.Executor {
+				io.In = pipe
+				return helpers.NewExecutor("", io)
+			},
+			expectConnect: true,
+		},
+		{
+			name: "no stdin",
+			executor: func(io fabricator.IOStreams) *helpers.Executor {
+				io.In = nil
+				return helpers.NewExecutor("", io)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			io := fabricator.NewTestIOStreamsDiscard()
+			runner := helpers.NewFakeRunner().Reply("", "cat")
+			executor := test.executor(io).WithRunner(runner)
+
+			done := make(chan error, 1)
+			go func() { done <- executor.Run(context.Background(), "cat") }()
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("want the command run without reading stdin to its end")
+			}
+
+			invocations := runner.Invocations()
+			if len(invocations) != 1 {
+				t.Fatalf("want one invocation, have %v", invocations)
+			}
+			if string(invocations[0].Stdin) != test.expectStdin || invocations[0].StdinConnected != test.expectConnect {
+				t.Errorf("want stdin %q, connected %v, have %q, %v", test.expectStdin, test.expectConnect, invocations[0].Stdin, invocations[0].StdinConnected)
+			}
+		})
+	}
+}
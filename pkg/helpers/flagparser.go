@@ -1,17 +1,58 @@
 package helpers
 
 import (
-	"os"
-
 	"code.cestus.io/tools/fabricator/pkg/fabricator"
 	"code.cestus.io/tools/fabricator/pkg/ff"
+	"code.cestus.io/tools/fabricator/pkg/ff/ffauto"
 	"code.cestus.io/tools/fabricator/pkg/ff/ffpflag"
 	"github.com/spf13/cobra"
 )
 
-var DefaultFlagParser fabricator.FlagParser = func(cmd *cobra.Command) error {
-	flagset := ffpflag.NewFlagSet(cmd.Flags())
-	return ff.Parse(flagset, os.Args[1:],
-		ff.WithEnvVarPrefix("fabricator"),
-	)
+// DefaultFlagParser reads flags from the commandline carried by the context of
+// the command (see fabricator.ArgsFromContext), from environment variables
+// prefixed with FABRICATOR_ and from the config file named by the --config
+// flag of fabricator.RootOptions, in that priority order. Errors are returned
+// as fabricator.ConfigError. Flags of commands which cobra parsed already are
+// not read from the commandline again.
+var DefaultFlagParser fabricator.FlagParser = NewFlagParser()
+
+// NewFlagParser returns a FlagParser behaving like DefaultFlagParser, with the
+// given options (e.g. ff.WithRequired or ff.WithValidator) applied in addition.
+//
+// The config file format is chosen by ffauto.ElementParser. Since one config file is
+// shared by fabricator and all its plugins, keys which the parsed command does
+// not define are ignored; pass ff.WithIgnoreUndefined(false) to reject them.
+func NewFlagParser(options ...ff.Option) fabricator.FlagParser {
+	return func(cmd *cobra.Command) error {
+		var flagset ff.FlagSet = ffpflag.NewFlagSet(cmd.Flags())
+		args := fabricator.ArgsFromContext(cmd.Context())
+		if cmd.Flags().Parsed() {
+			// cobra parsed the arguments of cmd already, which are not
+			// necessarily those of the process; parsing the commandline again
+			// would also append the values of slice flags a second time.
+			flagset, args = parsedFlagSet{ffpflag.NewFlagSet(cmd.Flags())}, nil
+		}
+		err := ff.Parse(flagset, args,
+			append([]ff.Option{
+				ff.WithEnvVarPrefix("fabricator"),
+				ff.WithConfigFileFlag("config"),
+				ff.WithConfigFileElementParser(ffauto.ElementParser),
+				ff.WithIgnoreUndefined(true),
+			}, options...)...,
+		)
+		if err != nil {
+			return &fabricator.ConfigError{Err: err}
+		}
+		return nil
+	}
+}
+
+// parsedFlagSet is a flag set whose commandline is parsed already.
+type parsedFlagSet struct {
+	*ffpflag.FlagSet
+}
+
+// Parse implements ff.FlagSet. It leaves the flag set as it is.
+func (fs parsedFlagSet) Parse([]string) error {
+	return nil
 }
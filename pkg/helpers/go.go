@@ -2,7 +2,9 @@ package helpers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"go/build"
 	"os"
 	"path"
 	"path/filepath"
@@ -11,21 +13,91 @@ import (
 	"code.cestus.io/tools/fabricator/pkg/fabricator"
 )
 
-// GuessGoImportPath guesses to Go import path for the specified folder.
-func GuessGoImportPath(ctx context.Context, io fabricator.IOStreams, root string) (goImportPath string, err error) {
+// GoOption configures the Go helpers.
+type GoOption func(*goOptions)
+
+type goOptions struct {
+	goWork bool
+}
+
+// WithGoWork makes the Go helpers honour the go.work of the workspace, found
+// the way the go command finds it (see FindGoWorkspace), if honour is true.
+// Directories then resolve to workspace modules only, and import paths to
+// packages of any workspace module. By default go.work files are ignored, as
+// with GOWORK=off.
+func WithGoWork(honour bool) GoOption {
+	return func(o *goOptions) {
+		o.goWork = honour
+	}
+}
+
+func newGoOptions(options []GoOption) goOptions {
+	var o goOptions
+	for _, option := range options {
+		option(&o)
+	}
+	return o
+}
+
+// workspace returns the workspace containing dir, or nil if go.work is not
+// honoured or there is none.
+func (o goOptions) workspace(dir string) (*GoWorkspace, error) {
+	if !o.goWork {
+		return nil, nil
+	}
+	ws, err := FindGoWorkspace(dir)
+	if errors.Is(err, ErrNoGoWorkspace) {
+		return nil, nil
+	}
+	return ws, err
+}
+
+// findModule returns the module owning dir, within the workspace if go.work
+// is honoured.
+func (o goOptions) findModule(dir string) (*GoModule, error) {
+	ws, err := o.workspace(dir)
+	if err != nil {
+		return nil, err
+	}
+	if ws != nil {
+		return ws.Module(dir)
+	}
+	return FindGoModule(dir)
+}
+
+// executor returns the Executor for go commands run as fallback.
+func (o goOptions) executor(root string, io fabricator.IOStreams) *Executor {
+	executor := NewExecutor(root, io)
+	if !o.goWork {
+		executor = executor.WithEnv("GOWORK", "off")
+	}
+	return executor
+}
+
+// GuessGoImportPath guesses to Go import path for the specified folder. It is
+// derived from the nearest go.mod; the go command is only asked if there is
+// none.
+func GuessGoImportPath(ctx context.Context, io fabricator.IOStreams, root string, options ...GoOption) (goImportPath string, err error) {
 	defer func() {
 		if err != nil {
-			err = fmt.Errorf("guessing Go import path at `%s`: %s", root, err)
+			err = fmt.Errorf("guessing Go import path at `%s`: %w", root, err)
 		}
 	}()
 
-	executor := NewExecutor(root, io).WithEnv("GOWORK", "off")
+	o := newGoOptions(options)
+	if mod, err := o.findModule(root); err == nil {
+		return mod.GetRelativeImportPath(root)
+	} else if !errors.Is(err, ErrNoGoModule) {
+		return "", err
+	}
+
+	executor := o.executor(root, io)
 	// If a Go import path is defined, use it.
 	if goImportPath, err = executor.Output(ctx, "go", "list", "-f", "{{ .ImportPath }}"); err == nil {
 		return strings.TrimSpace(goImportPath), nil
 	}
 
-	mod, err := GetGoModule(ctx, io, root)
+	mod, err := goListModule(ctx, executor)
 
 	if err != nil {
 		return "", err
@@ -34,9 +106,16 @@ func GuessGoImportPath(ctx context.Context, io fabricator.IOStreams, root string
 	return mod.GetRelativeImportPath(root)
 }
 
+// GoModule describes a Go module, or a package within one.
 type GoModule struct {
+	// Path is the module path, or the import path of a package.
 	Path string `json:"Path"`
-	Dir  string `json:"Dir"`
+	// Dir is the absolute directory of the module or package.
+	Dir string `json:"Dir"`
+	// GoMod is the absolute path of the go.mod file, if known.
+	GoMod string `json:"GoMod,omitempty"`
+	// GoVersion is the version of the go directive, if known.
+	GoVersion string `json:"GoVersion,omitempty"`
 }
 
 func (m GoModule) String() string {
@@ -46,52 +125,136 @@ func (m GoModule) String() string {
 func (m GoModule) GetRelativeImportPath(dir string) (_ string, err error) {
 	defer func() {
 		if err != nil {
-			err = fmt.Errorf("getting relative import path for `%s`: %s", dir, err)
+			err = fmt.Errorf("getting relative import path for `%s`: %w", dir, err)
 		}
 	}()
 
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
 	relPath, err := filepath.Rel(m.Dir, dir)
 
 	if err != nil {
-		return "", fmt.Errorf("unable to determine relative path: %s", err)
+		return "", fmt.Errorf("unable to determine relative path: %w", err)
+	}
+
+	if relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("directory is outside module %s at `%s`", m.Path, m.Dir)
 	}
 
 	return path.Join(m.Path, filepath.ToSlash(relPath)), nil
 }
 
-func GetGoModule(ctx context.Context, io fabricator.IOStreams, root string) (_ *GoModule, err error) {
+// GetGoModule returns the module containing root. It is read from the
+// nearest go.mod; the go command is only asked if there is none.
+func GetGoModule(ctx context.Context, io fabricator.IOStreams, root string, options ...GoOption) (_ *GoModule, err error) {
 	defer func() {
 		if err != nil {
-			err = fmt.Errorf("getting Go module at `%s`: %s", root, err)
+			err = fmt.Errorf("getting Go module at `%s`: %w", root, err)
 		}
 	}()
 
-	executor := NewExecutor(root, io).WithEnv("GOWORK", "off")
+	o := newGoOptions(options)
+	if mod, err := o.findModule(root); !errors.Is(err, ErrNoGoModule) {
+		return mod, err
+	}
 
+	return goListModule(ctx, o.executor(root, io))
+}
+
+func goListModule(ctx context.Context, executor *Executor) (*GoModule, error) {
 	var result GoModule
-	err = executor.JSONOutput(ctx, &result, "go", "list", "-m", "-json")
+	if err := executor.JSONOutput(ctx, &result, "go", "list", "-m", "-json"); err != nil {
+		return nil, err
+	}
 
 	return &result, nil
 }
 
-func GetGoPackage(ctx context.Context, io fabricator.IOStreams, pkg string) (_ *GoModule, err error) {
+// GetGoPackage returns the import path and directory of the package pkg,
+// given as import path or as path relative to the working directory. Packages
+// of the module in the working directory, or of any workspace module if
+// go.work is honoured, are resolved from their go.mod; the go command is only
+// asked for others.
+func GetGoPackage(ctx context.Context, io fabricator.IOStreams, pkg string, options ...GoOption) (_ *GoModule, err error) {
 	defer func() {
 		if err != nil {
-			err = fmt.Errorf("getting Go package for `%s`: %s", pkg, err)
+			err = fmt.Errorf("getting Go package for `%s`: %w", pkg, err)
 		}
 	}()
 
 	wd, err := os.Getwd()
 
 	if err != nil {
-		return nil, fmt.Errorf("could not determine working directory")
+		return nil, fmt.Errorf("could not determine working directory: %w", err)
 	}
 
-	executor := NewExecutor(wd, io).WithEnv("GOWORK", "off")
-	var result GoModule
-	err = executor.JSONOutput(ctx, &result, "go", "list", "-json", pkg)
+	o := newGoOptions(options)
+	ws, err := o.workspace(wd)
+	if err != nil {
+		return nil, err
+	}
 
-	return &result, nil
+	var mod *GoModule
+	switch {
+	case ws != nil && build.IsLocalImport(pkg):
+		mod, err = ws.Module(filepath.Join(wd, filepath.FromSlash(pkg)))
+	case ws != nil:
+		mod, _ = ws.ModuleByPath(pkg)
+	default:
+		mod, err = FindGoModule(wd)
+	}
+	if err != nil && !errors.Is(err, ErrNoGoModule) {
+		return nil, err
+	}
+	if mod != nil {
+		if result, ok := mod.resolvePackage(wd, pkg); ok {
+			return result, nil
+		}
+	}
+
+	executor := o.executor(wd, io)
+	var result struct {
+		ImportPath string `json:"ImportPath"`
+		Dir        string `json:"Dir"`
+	}
+	if err = executor.JSONOutput(ctx, &result, "go", "list", "-json", pkg); err != nil {
+		return nil, err
+	}
+
+	return &GoModule{Path: result.ImportPath, Dir: result.Dir}, nil
+}
+
+// resolvePackage returns the package pkg of m, if pkg names an existing
+// directory of m, either as import path or relative to wd.
+func (m GoModule) resolvePackage(wd, pkg string) (*GoModule, bool) {
+	var dir string
+	switch {
+	case build.IsLocalImport(pkg):
+		dir = filepath.Join(wd, filepath.FromSlash(pkg))
+	case pkg == m.Path:
+		dir = m.Dir
+	case strings.HasPrefix(pkg, m.Path+"/"):
+		dir = filepath.Join(m.Dir, filepath.FromSlash(strings.TrimPrefix(pkg, m.Path+"/")))
+	default:
+		return nil, false
+	}
+
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, false
+	}
+	// Nested modules are not part of m.
+	if nested, err := FindGoModule(dir); err != nil || nested.Dir != m.Dir {
+		return nil, false
+	}
+
+	importPath, err := m.GetRelativeImportPath(dir)
+	if err != nil {
+		return nil, false
+	}
+	return &GoModule{Path: importPath, Dir: dir}, true
 }
 
 func GetGoPackageNameFromGoImportPath(goImportPath string) string {
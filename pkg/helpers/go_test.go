@@ -0,0 +1,332 @@
+package helpers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"code.cestus.io/tools/fabricator/pkg/fabricator"
+	"code.cestus.io/tools/fabricator/pkg/helpers"
+)
+
+func TestGuessGoImportPath(t *testing.T) {
+	moduleDir := t.TempDir()
+	module, err := json.Marshal(helpers.GoModule{Path: "example.com/m", Dir: moduleDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Join(moduleDir, "internal", "gen")
+
+	gomodDir := t.TempDir()
+	writeFile(t, filepath.Join(gomodDir, "go.mod"), "module example.com/m\n")
+
+	for _, testcase := range []struct {
+		name       string
+		root       string
+		runner     *helpers.FakeRunner
+		want       string
+		wantCalled []string
+	}{
+		{
+			name:   "go.mod without toolchain",
+			root:   filepath.Join(gomodDir, "internal", "gen"),
+			runner: helpers.NewFakeRunner(),
+			want:   "example.com/m/internal/gen",
+		},
+		{
+			name: "package",
+			runner: helpers.NewFakeRunner().
+				Reply("example.com/m/internal/gen\n", "go", "list", "-f", "{{ .ImportPath }}"),
+			want:       "example.com/m/internal/gen",
+			wantCalled: []string{`go list -f "{{ .ImportPath }}"`},
+		},
+		{
+			name: "directory without package",
+			runner: helpers.NewFakeRunner().
+				Fail(1, "no Go files\n", "go", "list", "-f", "{{ .ImportPath }}").
+				Reply(string(module), "go", "list", "-m", "-json"),
+			want:       "example.com/m/internal/gen",
+			wantCalled: []string{`go list -f "{{ .ImportPath }}"`, "go list -m -json"},
+		},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			ctx := helpers.ContextWithRunner(context.Background(), testcase.runner)
+			root := root
+			if testcase.root != "" {
+				root = testcase.root
+			}
+
+			have, err := helpers.GuessGoImportPath(ctx, fabricator.NewTestIOStreamsDiscard(), root)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if have != testcase.want {
+				t.Errorf("want import path %q, have %q", testcase.want, have)
+			}
+
+			invocations := testcase.runner.Invocations()
+			if len(invocations) != len(testcase.wantCalled) {
+				t.Fatalf("want commands %q, have %q", testcase.wantCalled, invocations)
+			}
+			for i, invocation := range invocations {
+				if invocation.String() != testcase.wantCalled[i] {
+					t.Errorf("want command %q, have %q", testcase.wantCalled[i], invocation)
+				}
+				if invocation.Dir != root {
+					t.Errorf("want command to run in %q, have %q", root, invocation.Dir)
+				}
+				if gowork := invocation.Getenv("GOWORK"); gowork != "off" {
+					t.Errorf("want GOWORK=off, have %q", gowork)
+				}
+			}
+		})
+	}
+}
+
+func TestGetGoModule(t *testing.T) {
+	moduleDir := t.TempDir()
+	writeFile(t, filepath.Join(moduleDir, "go.mod"), "module example.com/m\n\ngo 1.22\n")
+	nested := filepath.Join(moduleDir, "internal", "gen")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, testcase := range []struct {
+		name    string
+		root    string
+		runner  *helpers.FakeRunner
+		want    helpers.GoModule
+		wantErr string
+	}{
+		{
+			name:   "go.mod without toolchain",
+			root:   nested,
+			runner: helpers.NewFakeRunner(),
+			want: helpers.GoModule{
+				Path:      "example.com/m",
+				Dir:       moduleDir,
+				GoMod:     filepath.Join(moduleDir, "go.mod"),
+				GoVersion: "1.22",
+			},
+		},
+		{
+			name: "toolchain fallback",
+			root: t.TempDir(),
+			runner: helpers.NewFakeRunner().
+				Reply(`{"Path": "example.com/gopath", "Dir": "/src/gopath"}`, "go", "list", "-m", "-json"),
+			want: helpers.GoModule{Path: "example.com/gopath", Dir: "/src/gopath"},
+		},
+		{
+			name: "toolchain failure",
+			root: t.TempDir(),
+			runner: helpers.NewFakeRunner().
+				Fail(1, "go: go.mod file not found in current directory or any parent directory\n", "go", "list", "-m", "-json"),
+			wantErr: "go.mod file not found",
+		},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			ctx := helpers.ContextWithRunner(context.Background(), testcase.runner)
+
+			module, err := helpers.GetGoModule(ctx, fabricator.NewTestIOStreamsDiscard(), testcase.root)
+			if testcase.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), testcase.wantErr) {
+					t.Fatalf("want error containing %q, have %v", testcase.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if *module != testcase.want {
+				t.Errorf("want module %#v, have %#v", testcase.want, *module)
+			}
+		})
+	}
+}
+
+func TestGetGoPackage(t *testing.T) {
+	moduleDir := t.TempDir()
+	writeFile(t, filepath.Join(moduleDir, "go.mod"), "module example.com/m\n")
+	writeFile(t, filepath.Join(moduleDir, "api", "v1", "api.go"), "package v1\n")
+	writeFile(t, filepath.Join(moduleDir, "tools", "go.mod"), "module example.com/m/tools\n")
+	chdir(t, moduleDir)
+
+	for _, testcase := range []struct {
+		name   string
+		pkg    string
+		runner *helpers.FakeRunner
+		want   helpers.GoModule
+	}{
+		{
+			name:   "import path",
+			pkg:    "example.com/m/api/v1",
+			runner: helpers.NewFakeRunner(),
+			want:   helpers.GoModule{Path: "example.com/m/api/v1", Dir: filepath.Join(moduleDir, "api", "v1")},
+		},
+		{
+			name:   "relative path",
+			pkg:    "./api/v1",
+			runner: helpers.NewFakeRunner(),
+			want:   helpers.GoModule{Path: "example.com/m/api/v1", Dir: filepath.Join(moduleDir, "api", "v1")},
+		},
+		{
+			name: "dependency",
+			pkg:  "github.com/spf13/cobra",
+			runner: helpers.NewFakeRunner().
+				Reply(`{"ImportPath": "github.com/spf13/cobra", "Dir": "/mod/cobra"}`, "go", "list", "-json", "github.com/spf13/cobra"),
+			want: helpers.GoModule{Path: "github.com/spf13/cobra", Dir: "/mod/cobra"},
+		},
+		{
+			name: "nested module",
+			pkg:  "example.com/m/tools",
+			runner: helpers.NewFakeRunner().
+				Reply(`{"ImportPath": "example.com/m/tools", "Dir": "/elsewhere/tools"}`, "go", "list", "-json", "example.com/m/tools"),
+			want: helpers.GoModule{Path: "example.com/m/tools", Dir: "/elsewhere/tools"},
+		},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			ctx := helpers.ContextWithRunner(context.Background(), testcase.runner)
+
+			pkg, err := helpers.GetGoPackage(ctx, fabricator.NewTestIOStreamsDiscard(), testcase.pkg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if *pkg != testcase.want {
+				t.Errorf("want package %#v, have %#v", testcase.want, *pkg)
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(wd)
+	})
+}
+
+func TestGoWorkspace(t *testing.T) {
+	t.Setenv("GOWORK", "")
+	ws := t.TempDir()
+	writeFile(t, filepath.Join(ws, "go.work"), "go 1.22\n\nuse (\n\t./app\n\t./lib\n)\n")
+	writeFile(t, filepath.Join(ws, "app", "go.mod"), "module example.com/app\n")
+	writeFile(t, filepath.Join(ws, "app", "cmd", "app", "main.go"), "package main\n")
+	writeFile(t, filepath.Join(ws, "lib", "go.mod"), "module example.com/lib\n")
+	writeFile(t, filepath.Join(ws, "lib", "api", "api.go"), "package api\n")
+	writeFile(t, filepath.Join(ws, "unused", "go.mod"), "module example.com/unused\n")
+	io := fabricator.NewTestIOStreamsDiscard()
+
+	t.Run("FindGoWorkspace", func(t *testing.T) {
+		workspace, err := helpers.FindGoWorkspace(filepath.Join(ws, "app", "cmd"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var modules []string
+		for _, m := range workspace.Modules {
+			modules = append(modules, m.Path+" "+m.Dir)
+		}
+		want := []string{"example.com/app " + filepath.Join(ws, "app"), "example.com/lib " + filepath.Join(ws, "lib")}
+		if strings.Join(modules, "\n") != strings.Join(want, "\n") {
+			t.Errorf("want modules %q, have %q", want, modules)
+		}
+
+		t.Setenv("GOWORK", "off")
+		if _, err := helpers.FindGoWorkspace(ws); !errors.Is(err, helpers.ErrNoGoWorkspace) {
+			t.Errorf("want ErrNoGoWorkspace with GOWORK=off, have %v", err)
+		}
+	})
+
+	for _, testcase := range []struct {
+		name    string
+		root    string
+		options []helpers.GoOption
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "workspace module",
+			root:    filepath.Join(ws, "app", "cmd", "app"),
+			options: []helpers.GoOption{helpers.WithGoWork(true)},
+			want:    "example.com/app/cmd/app",
+		},
+		{
+			name:    "module not used by workspace",
+			root:    filepath.Join(ws, "unused"),
+			options: []helpers.GoOption{helpers.WithGoWork(true)},
+			wantErr: "not used by workspace",
+		},
+		{
+			name: "workspace ignored",
+			root: filepath.Join(ws, "unused"),
+			want: "example.com/unused",
+		},
+	} {
+		t.Run("GuessGoImportPath/"+testcase.name, func(t *testing.T) {
+			ctx := helpers.ContextWithRunner(context.Background(), helpers.NewFakeRunner())
+
+			have, err := helpers.GuessGoImportPath(ctx, io, testcase.root, testcase.options...)
+			if testcase.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), testcase.wantErr) {
+					t.Fatalf("want error containing %q, have %v", testcase.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if have != testcase.want {
+				t.Errorf("want import path %q, have %q", testcase.want, have)
+			}
+		})
+	}
+
+	t.Run("GetGoPackage", func(t *testing.T) {
+		chdir(t, filepath.Join(ws, "app"))
+		runner := helpers.NewFakeRunner().
+			Reply(`{"ImportPath": "example.com/lib/api", "Dir": "/mod/lib/api"}`, "go", "list", "-json", "example.com/lib/api")
+		ctx := helpers.ContextWithRunner(context.Background(), runner)
+
+		pkg, err := helpers.GetGoPackage(ctx, io, "example.com/lib/api", helpers.WithGoWork(true))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := filepath.Join(ws, "lib", "api"); pkg.Dir != want {
+			t.Errorf("want package in workspace module at %q, have %q", want, pkg.Dir)
+		}
+		if invocations := runner.Invocations(); len(invocations) != 0 {
+			t.Errorf("want no go command for workspace package, have %q", invocations)
+		}
+
+		pkg, err = helpers.GetGoPackage(ctx, io, "example.com/lib/api")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if pkg.Dir != "/mod/lib/api" {
+			t.Errorf("want package resolved by the go command without workspace, have %q", pkg.Dir)
+		}
+		if invocations := runner.Invocations(); len(invocations) != 1 || invocations[0].Getenv("GOWORK") != "off" {
+			t.Errorf("want go command with GOWORK=off, have %q", invocations)
+		}
+	})
+}
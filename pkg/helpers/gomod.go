@@ -0,0 +1,70 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/mod/modfile"
+)
+
+// ErrNoGoModule is returned when a directory is not inside a Go module.
+var ErrNoGoModule = errors.New("no go.mod found in directory or any parent")
+
+// FindGoModule returns the module containing dir by locating and parsing the
+// nearest go.mod file, without running the go command. It returns an error
+// wrapping ErrNoGoModule if there is no go.mod in dir or any parent.
+func FindGoModule(dir string) (*GoModule, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		gomod := filepath.Join(dir, "go.mod")
+		if info, err := os.Stat(gomod); err == nil && !info.IsDir() {
+			return ParseGoModule(gomod)
+		} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, fmt.Errorf("%w: %s", ErrNoGoModule, dir)
+		}
+		dir = parent
+	}
+}
+
+// ParseGoModule parses the go.mod file at gomod.
+func ParseGoModule(gomod string) (*GoModule, error) {
+	gomod, err := filepath.Abs(gomod)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(gomod)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := modfile.ParseLax(gomod, data, nil)
+	if err != nil {
+		return nil, err
+	}
+	if f.Module == nil {
+		return nil, fmt.Errorf("%s: no module directive", gomod)
+	}
+
+	module := &GoModule{
+		Path:  f.Module.Mod.Path,
+		Dir:   filepath.Dir(gomod),
+		GoMod: gomod,
+	}
+	if f.Go != nil {
+		module.GoVersion = f.Go.Version
+	}
+	return module, nil
+}
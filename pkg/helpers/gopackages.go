@@ -0,0 +1,203 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/types"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// GoPackage is a Go package loaded by LoadGoPackages.
+type GoPackage struct {
+	// Name is the package name and Path its import path.
+	Name string
+	Path string
+	// Dir is the directory of the package.
+	Dir string
+	// Files are the absolute names of the package's Go files, tests excluded.
+	Files []string
+	// Types are the exported named types of the package, sorted by name.
+	Types []GoType
+	// Package is the type-checked package, for anything the model does not
+	// cover.
+	Package *types.Package
+}
+
+// Type returns the exported type called name.
+func (p *GoPackage) Type(name string) (GoType, bool) {
+	for _, t := range p.Types {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return GoType{}, false
+}
+
+// GoTypeKind is the kind of the underlying type of a GoType.
+type GoTypeKind int
+
+const (
+	GoOther GoTypeKind = iota
+	GoStruct
+	GoInterface
+)
+
+func (k GoTypeKind) String() string {
+	switch k {
+	case GoStruct:
+		return "struct"
+	case GoInterface:
+		return "interface"
+	default:
+		return "other"
+	}
+}
+
+// GoType is an exported named type of a GoPackage.
+type GoType struct {
+	Name string
+	Kind GoTypeKind
+	// Underlying is the underlying type, e.g. "int" for an enum; for structs
+	// and interfaces Fields and Methods are more convenient.
+	Underlying string
+	// Fields are the fields of a struct, in declaration order.
+	Fields []GoField
+	// Methods are the method set of the type, including promoted methods and,
+	// for non-interface types, the methods of its pointer. They are sorted by
+	// name.
+	Methods []GoMethod
+	// Object is the type-checked type.
+	Object *types.TypeName
+}
+
+// GoField is a field of a struct.
+type GoField struct {
+	Name string
+	// Type is the field type, qualified by package name unless it is declared
+	// in the same package, as it would be written in its source.
+	Type     string
+	Tag      reflect.StructTag
+	Embedded bool
+}
+
+// GoMethod is a method of a GoType.
+type GoMethod struct {
+	Name string
+	// Signature is the method signature without the func keyword, e.g.
+	// "(ctx context.Context) error", qualified like GoField.Type.
+	Signature string
+	// PointerReceiver is set for methods declared on the pointer type.
+	PointerReceiver bool
+}
+
+// LoadGoPackages loads and type-checks the packages matching patterns, e.g.
+// "./..." or import paths, relative to dir. It needs the go command. Errors of
+// all packages are reported together.
+func LoadGoPackages(ctx context.Context, dir string, patterns []string, options ...GoOption) ([]*GoPackage, error) {
+	o := newGoOptions(options)
+	env := os.Environ()
+	if !o.goWork {
+		env = append(env, "GOWORK=off")
+	}
+
+	cfg := &packages.Config{
+		Context: ctx,
+		Dir:     dir,
+		Env:     env,
+		// Type-check dependencies from source too: export data written by a
+		// newer go command than x/tools knows cannot be read.
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps |
+			packages.NeedTypes | packages.NeedSyntax,
+	}
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("loading Go packages %s at `%s`: %w", strings.Join(patterns, " "), dir, err)
+	}
+
+	var errs []error
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		for _, err := range pkg.Errors {
+			errs = append(errs, err)
+		}
+	})
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("loading Go packages %s at `%s`: %w", strings.Join(patterns, " "), dir, errors.Join(errs...))
+	}
+
+	result := make([]*GoPackage, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		result = append(result, newGoPackage(pkg))
+	}
+	return result, nil
+}
+
+func newGoPackage(pkg *packages.Package) *GoPackage {
+	p := &GoPackage{
+		Name:    pkg.Name,
+		Path:    pkg.PkgPath,
+		Dir:     pkg.Dir,
+		Files:   pkg.GoFiles,
+		Package: pkg.Types,
+	}
+
+	qualifier := types.RelativeTo(pkg.Types)
+	scope := pkg.Types.Scope()
+	names := scope.Names()
+	sort.Strings(names)
+	for _, name := range names {
+		obj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || !obj.Exported() {
+			continue
+		}
+		p.Types = append(p.Types, newGoType(obj, qualifier))
+	}
+	return p
+}
+
+func newGoType(obj *types.TypeName, qualifier types.Qualifier) GoType {
+	t := GoType{
+		Name:       obj.Name(),
+		Underlying: types.TypeString(obj.Type().Underlying(), qualifier),
+		Object:     obj,
+	}
+
+	var methodSetOf types.Type = types.NewPointer(obj.Type())
+	switch underlying := obj.Type().Underlying().(type) {
+	case *types.Struct:
+		t.Kind = GoStruct
+		for i := 0; i < underlying.NumFields(); i++ {
+			field := underlying.Field(i)
+			t.Fields = append(t.Fields, GoField{
+				Name:     field.Name(),
+				Type:     types.TypeString(field.Type(), qualifier),
+				Tag:      reflect.StructTag(underlying.Tag(i)),
+				Embedded: field.Embedded(),
+			})
+		}
+	case *types.Interface:
+		t.Kind = GoInterface
+		methodSetOf = obj.Type()
+	}
+
+	methods := types.NewMethodSet(methodSetOf)
+	for i := 0; i < methods.Len(); i++ {
+		fn := methods.At(i).Obj().(*types.Func)
+		sig := fn.Type().(*types.Signature)
+		method := GoMethod{
+			Name:      fn.Name(),
+			Signature: strings.TrimPrefix(types.TypeString(sig, qualifier), "func"),
+		}
+		if recv := sig.Recv(); recv != nil {
+			_, method.PointerReceiver = recv.Type().(*types.Pointer)
+		}
+		t.Methods = append(t.Methods, method)
+	}
+	sort.Slice(t.Methods, func(i, j int) bool { return t.Methods[i].Name < t.Methods[j].Name })
+	return t
+}
@@ -0,0 +1,132 @@
+package helpers_test
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"code.cestus.io/tools/fabricator/pkg/helpers"
+)
+
+const modelSource = `package model
+
+import "context"
+
+type Color int
+
+func (c Color) String() string { return "" }
+
+type Base struct {
+	ID string ` + "`json:\"id\"`" + `
+}
+
+func (b *Base) Key() string { return b.ID }
+
+type User struct {
+	Base
+	Name  string            ` + "`json:\"name\" yaml:\"name\"`" + `
+	Color Color
+	Tags  map[string]string
+	ctx   context.Context
+}
+
+type Store interface {
+	Get(ctx context.Context, id string) (*User, error)
+	Close() error
+}
+
+type unexported struct{}
+`
+
+func TestLoadGoPackages(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not available")
+	}
+	t.Setenv("GOFLAGS", "")
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/m\n\ngo 1.22\n")
+	writeFile(t, filepath.Join(dir, "model", "model.go"), modelSource)
+	writeFile(t, filepath.Join(dir, "model", "model_test.go"), "package model\n")
+
+	pkgs, err := helpers.LoadGoPackages(context.Background(), dir, []string{"./..."})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pkgs) != 1 {
+		t.Fatalf("want one package, have %d", len(pkgs))
+	}
+	pkg := pkgs[0]
+
+	if pkg.Name != "model" || pkg.Path != "example.com/m/model" || pkg.Dir != filepath.Join(dir, "model") {
+		t.Errorf("want package model at example.com/m/model, have %s at %s in %s", pkg.Name, pkg.Path, pkg.Dir)
+	}
+	if want := []string{filepath.Join(dir, "model", "model.go")}; !reflect.DeepEqual(pkg.Files, want) {
+		t.Errorf("want files %q, have %q", want, pkg.Files)
+	}
+
+	var names []string
+	for _, typ := range pkg.Types {
+		names = append(names, typ.Name+":"+typ.Kind.String())
+	}
+	if want := "Base:struct Color:other Store:interface User:struct"; strings.Join(names, " ") != want {
+		t.Errorf("want types %q, have %q", want, strings.Join(names, " "))
+	}
+
+	color, _ := pkg.Type("Color")
+	if color.Underlying != "int" {
+		t.Errorf("want Color to be an int, have %q", color.Underlying)
+	}
+
+	user, _ := pkg.Type("User")
+	wantFields := []helpers.GoField{
+		{Name: "Base", Type: "Base", Embedded: true},
+		{Name: "Name", Type: "string", Tag: `json:"name" yaml:"name"`},
+		{Name: "Color", Type: "Color"},
+		{Name: "Tags", Type: "map[string]string"},
+		{Name: "ctx", Type: "context.Context"},
+	}
+	if !reflect.DeepEqual(user.Fields, wantFields) {
+		t.Errorf("want fields %+v, have %+v", wantFields, user.Fields)
+	}
+	if tag := user.Fields[1].Tag.Get("yaml"); tag != "name" {
+		t.Errorf("want yaml tag name, have %q", tag)
+	}
+	if want := []helpers.GoMethod{{Name: "Key", Signature: "() string", PointerReceiver: true}}; !reflect.DeepEqual(user.Methods, want) {
+		t.Errorf("want promoted methods %+v, have %+v", want, user.Methods)
+	}
+
+	store, _ := pkg.Type("Store")
+	wantMethods := []helpers.GoMethod{
+		{Name: "Close", Signature: "() error"},
+		{Name: "Get", Signature: "(ctx context.Context, id string) (*User, error)"},
+	}
+	if !reflect.DeepEqual(store.Methods, wantMethods) {
+		t.Errorf("want methods %+v, have %+v", wantMethods, store.Methods)
+	}
+}
+
+func TestLoadGoPackagesErrors(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not available")
+	}
+	t.Setenv("GOFLAGS", "")
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/m\n")
+	writeFile(t, filepath.Join(dir, "a", "a.go"), "package a\n\nvar A int = \"a\"\n")
+	writeFile(t, filepath.Join(dir, "b", "b.go"), "package b\n\nfunc B() { undefined() }\n")
+
+	_, err := helpers.LoadGoPackages(context.Background(), dir, []string{"./..."})
+	if err == nil {
+		t.Fatal("want error for broken packages, have none")
+	}
+	for _, want := range []string{"a.go:3", "b.go:3"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("want error mentioning %s, have %v", want, err)
+		}
+	}
+}
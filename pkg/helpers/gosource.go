@@ -0,0 +1,113 @@
+package helpers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/imports"
+)
+
+// GoSourceError is returned for generated Go source which does not parse.
+type GoSourceError struct {
+	// Generator is the name of the generator which produced the source.
+	Generator string
+	// File is the name the source was to be written to.
+	File string
+	// Line and Column locate the first syntax error.
+	Line   int
+	Column int
+	// Source is the offending line.
+	Source string
+	// Err is the scanner.ErrorList of all syntax errors.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *GoSourceError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "generator %s produced invalid Go source: %s", e.Generator, e.Err)
+	if e.Source != "" {
+		fmt.Fprintf(&b, "\n\t%d: %s", e.Line, e.Source)
+	}
+	return b.String()
+}
+
+func (e *GoSourceError) Unwrap() error {
+	return e.Err
+}
+
+// GoSourceFormatter post-processes generated Go source files: it validates
+// that they parse, fixes their imports the way goimports does and formats
+// them with gofmt.
+type GoSourceFormatter struct {
+	// Generator is reported in errors, e.g. "fabricator-mock".
+	Generator string
+	// KeepImports disables adding missing and removing unused imports, which
+	// may need to consult the go command to find packages.
+	KeepImports bool
+}
+
+// Format returns src post-processed. filename is used in error messages and
+// to resolve imports relative to its directory and module. Source which does
+// not parse is returned as a *GoSourceError.
+func (f GoSourceFormatter) Format(filename string, src []byte) ([]byte, error) {
+	if err := f.validate(filename, src); err != nil {
+		return nil, err
+	}
+
+	out, err := imports.Process(filename, src, &imports.Options{
+		Comments:   true,
+		TabIndent:  true,
+		TabWidth:   8,
+		FormatOnly: f.KeepImports,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("generator %s: fixing imports of %s: %w", f.Generator, filename, err)
+	}
+
+	// imports only sorts the imports it touches; gofmt has the last word.
+	out, err = format.Source(out)
+	if err != nil {
+		return nil, fmt.Errorf("generator %s: formatting %s: %w", f.Generator, filename, err)
+	}
+	return out, nil
+}
+
+// WriteFile post-processes src like Format and writes it to filename,
+// creating missing directories. Nothing is written if src does not parse.
+func (f GoSourceFormatter) WriteFile(filename string, src []byte, perm os.FileMode) error {
+	out, err := f.Format(filename, src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, out, perm)
+}
+
+func (f GoSourceFormatter) validate(filename string, src []byte) error {
+	_, err := parser.ParseFile(token.NewFileSet(), filename, src, parser.ParseComments|parser.AllErrors)
+	if err == nil {
+		return nil
+	}
+
+	sourceErr := &GoSourceError{Generator: f.Generator, File: filename, Err: err}
+	var list scanner.ErrorList
+	if errors.As(err, &list) && len(list) > 0 {
+		sourceErr.Line, sourceErr.Column = list[0].Pos.Line, list[0].Pos.Column
+		lines := bytes.Split(src, []byte("\n"))
+		if sourceErr.Line > 0 && sourceErr.Line <= len(lines) {
+			sourceErr.Source = strings.TrimSpace(string(lines[sourceErr.Line-1]))
+		}
+	}
+	return sourceErr
+}
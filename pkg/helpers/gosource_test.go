@@ -0,0 +1,74 @@
+package helpers_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"code.cestus.io/tools/fabricator/pkg/helpers"
+)
+
+func TestGoSourceFormatter(t *testing.T) {
+	const unformatted = "package gen\n\nimport (\n\"os\"\n\"fmt\"\n)\n\nfunc Hello( ) {\nfmt.Println(\"hello\")\n}\n"
+
+	for _, testcase := range []struct {
+		name      string
+		formatter helpers.GoSourceFormatter
+		src       string
+		want      string
+		wantErr   []string
+	}{
+		{
+			name:      "fix imports",
+			formatter: helpers.GoSourceFormatter{Generator: "test"},
+			src:       unformatted,
+			want:      "package gen\n\nimport (\n\t\"fmt\"\n)\n\nfunc Hello() {\n\tfmt.Println(\"hello\")\n}\n",
+		},
+		{
+			name:      "keep imports",
+			formatter: helpers.GoSourceFormatter{Generator: "test", KeepImports: true},
+			src:       unformatted,
+			want:      "package gen\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\nfunc Hello() {\n\tfmt.Println(\"hello\")\n}\n",
+		},
+		{
+			name:      "syntax error",
+			formatter: helpers.GoSourceFormatter{Generator: "fabricator-mock"},
+			src:       "package gen\n\nfunc Hello() {\n\treturn 1 +\n}\n",
+			wantErr:   []string{"generator fabricator-mock produced invalid Go source", "gen.go:5:1", "5: }"},
+		},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "gen", "gen.go")
+
+			err := testcase.formatter.WriteFile(filename, []byte(testcase.src), 0o644)
+			if testcase.wantErr != nil {
+				var sourceErr *helpers.GoSourceError
+				if !errors.As(err, &sourceErr) {
+					t.Fatalf("want GoSourceError, have %v", err)
+				}
+				for _, want := range testcase.wantErr {
+					if !strings.Contains(err.Error(), want) {
+						t.Errorf("want error containing %q, have %q", want, err)
+					}
+				}
+				if _, err := os.Stat(filename); !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("want no file written for invalid source, have %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			have, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(have) != testcase.want {
+				t.Errorf("want\n%s\nhave\n%s", testcase.want, have)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// ErrNoGoWorkspace is returned when a directory is not inside a Go workspace,
+// or workspaces are disabled with GOWORK=off.
+var ErrNoGoWorkspace = errors.New("no go.work found in directory or any parent")
+
+// GoWorkspace describes a Go workspace defined by a go.work file.
+type GoWorkspace struct {
+	// GoWork is the absolute path of the go.work file.
+	GoWork string
+	// Dir is the directory of the go.work file.
+	Dir string
+	// GoVersion is the version of the go directive, if any.
+	GoVersion string
+	// Modules are the modules of the use directives, in file order.
+	Modules []GoModule
+}
+
+// FindGoWorkspace returns the workspace containing dir the way the go command
+// finds it: from the GOWORK environment variable if it is set, else from the
+// nearest go.work in dir or any parent. It returns an error wrapping
+// ErrNoGoWorkspace if there is none or GOWORK is "off".
+func FindGoWorkspace(dir string) (*GoWorkspace, error) {
+	switch gowork := os.Getenv("GOWORK"); gowork {
+	case "off":
+		return nil, fmt.Errorf("%w: GOWORK=off", ErrNoGoWorkspace)
+	case "", "auto":
+	default:
+		return ParseGoWorkspace(gowork)
+	}
+
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		gowork := filepath.Join(dir, "go.work")
+		if info, err := os.Stat(gowork); err == nil && !info.IsDir() {
+			return ParseGoWorkspace(gowork)
+		} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, fmt.Errorf("%w: %s", ErrNoGoWorkspace, dir)
+		}
+		dir = parent
+	}
+}
+
+// ParseGoWorkspace parses the go.work file at gowork and the go.mod files of
+// its use directives.
+func ParseGoWorkspace(gowork string) (*GoWorkspace, error) {
+	gowork, err := filepath.Abs(gowork)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(gowork)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := modfile.ParseWork(gowork, data, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ws := &GoWorkspace{
+		GoWork: gowork,
+		Dir:    filepath.Dir(gowork),
+	}
+	if f.Go != nil {
+		ws.GoVersion = f.Go.Version
+	}
+	for _, use := range f.Use {
+		dir := filepath.FromSlash(use.Path)
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(ws.Dir, dir)
+		}
+		mod, err := ParseGoModule(filepath.Join(dir, "go.mod"))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: use %s: %w", gowork, use.Syntax.Start.Line, use.Path, err)
+		}
+		ws.Modules = append(ws.Modules, *mod)
+	}
+	return ws, nil
+}
+
+// Module returns the workspace module owning dir, i.e. the module of the
+// nearest go.mod, which must be used by the workspace.
+func (ws *GoWorkspace) Module(dir string) (*GoModule, error) {
+	mod, err := FindGoModule(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range ws.Modules {
+		if m.Dir == mod.Dir {
+			return &m, nil
+		}
+	}
+	return nil, fmt.Errorf("module %s at `%s` is not used by workspace `%s`", mod.Path, mod.Dir, ws.GoWork)
+}
+
+// ModuleByPath returns the workspace module providing the package with the
+// given import path, i.e. the one with the longest matching module path.
+func (ws *GoWorkspace) ModuleByPath(importPath string) (*GoModule, bool) {
+	var best *GoModule
+	for i, m := range ws.Modules {
+		if importPath != m.Path && !strings.HasPrefix(importPath, m.Path+"/") {
+			continue
+		}
+		if best == nil || len(m.Path) > len(best.Path) {
+			best = &ws.Modules[i]
+		}
+	}
+	return best, best != nil
+}
@@ -0,0 +1,91 @@
+package helpers
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Stream identifies an output stream of a command.
+type Stream int
+
+const (
+	Stdout Stream = iota + 1
+	Stderr
+)
+
+func (s Stream) String() string {
+	switch s {
+	case Stdout:
+		return "stdout"
+	case Stderr:
+		return "stderr"
+	default:
+		return "unknown"
+	}
+}
+
+// LineFunc is called by an Executor with every line a command writes to
+// stream, without the line ending. Calls are never concurrent.
+type LineFunc func(stream Stream, line string)
+
+// lineWriter is an io.Writer which splits what is written to it into lines,
+// passes them to fn and writes them to w with prefix in front.
+type lineWriter struct {
+	// mu is shared by the writers of one command to serialize calls to fn.
+	mu     *sync.Mutex
+	stream Stream
+	w      io.Writer
+	prefix string
+	fn     LineFunc
+	buf    []byte
+}
+
+// Write implements io.Writer.
+func (l *lineWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.buf = append(l.buf, p...)
+	rest := l.buf
+	for {
+		i := bytes.IndexByte(rest, '\n')
+		if i < 0 {
+			break
+		}
+		if err := l.emit(rest[:i+1]); err != nil {
+			return 0, err
+		}
+		rest = rest[i+1:]
+	}
+	l.buf = append(l.buf[:0], rest...)
+	return len(p), nil
+}
+
+// Flush emits the last line if it has no line ending.
+func (l *lineWriter) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.buf) == 0 {
+		return nil
+	}
+	err := l.emit(l.buf)
+	l.buf = l.buf[:0]
+	return err
+}
+
+func (l *lineWriter) emit(line []byte) error {
+	if l.fn != nil {
+		l.fn(l.stream, strings.TrimRight(string(line), "\r\n"))
+	}
+	if l.w == nil {
+		return nil
+	}
+	if _, err := io.WriteString(l.w, l.prefix); err != nil {
+		return err
+	}
+	_, err := l.w.Write(line)
+	return err
+}
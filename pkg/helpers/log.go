@@ -0,0 +1,26 @@
+package helpers
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	"code.cestus.io/tools/fabricator/pkg/fabricator"
+	"github.com/spf13/cobra"
+)
+
+// NewLoggerFromFlags returns a logger writing to w at the verbosity and in the
+// format of the --verbosity and --log-format flags of fabricator.RootOptions,
+// as read by flagparser from the commandline carried by ctx (see
+// fabricator.ArgsFromContext). It is meant to be called before the command is
+// executed; flags unknown to fabricator.RootOptions are skipped, and flags
+// which cannot be parsed are left to the command to report.
+func NewLoggerFromFlags(ctx context.Context, w io.Writer, flagparser fabricator.FlagParser) (*slog.Logger, error) {
+	cmd := &cobra.Command{}
+	cmd.SetContext(ctx)
+	var o fabricator.RootOptions
+	o.RegisterOptions(cmd.Flags())
+	cmd.Flags().ParseErrorsWhitelist.UnknownFlags = true
+	_ = flagparser(cmd)
+	return fabricator.NewLogger(w, o.Verbosity, o.LogFormat)
+}
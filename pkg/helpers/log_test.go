@@ -0,0 +1,80 @@
+package helpers_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"code.cestus.io/tools/fabricator/pkg/fabricator"
+	"code.cestus.io/tools/fabricator/pkg/helpers"
+)
+
+func TestNewLoggerFromFlags(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		args      []string
+		expect    []string
+		expectErr string
+	}{
+		{
+			name:   "warnings by default",
+			expect: []string{"level=WARN msg=warn\n", "level=ERROR msg=error\n"},
+		},
+		{
+			name:   "debug at verbosity 2",
+			env:    map[string]string{"FABRICATOR_VERBOSITY": "2"},
+			expect: []string{"level=DEBUG msg=debug\n", "level=INFO msg=info\n", "level=WARN msg=warn\n", "level=ERROR msg=error\n"},
+		},
+		{
+			name:   "debug with -vv",
+			args:   []string{"-vv", "--unknown"},
+			expect: []string{"level=DEBUG msg=debug\n", "level=INFO msg=info\n", "level=WARN msg=warn\n", "level=ERROR msg=error\n"},
+		},
+		{
+			name:   "json",
+			env:    map[string]string{"FABRICATOR_VERBOSITY": "1", "FABRICATOR_LOG_FORMAT": "json"},
+			expect: []string{`"level":"INFO","msg":"info"}`, `"level":"WARN","msg":"warn"}`, `"level":"ERROR","msg":"error"}`},
+		},
+		{
+			name:      "unsupported format",
+			env:       map[string]string{"FABRICATOR_LOG_FORMAT": "xml"},
+			expectErr: `unsupported log format "xml", must be text or json`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for k, v := range test.env {
+				t.Setenv(k, v)
+			}
+
+			var out bytes.Buffer
+			ctx := fabricator.ContextWithArgs(context.Background(), test.args)
+			logger, err := helpers.NewLoggerFromFlags(ctx, &out, helpers.DefaultFlagParser)
+			if test.expectErr != "" {
+				if err == nil || err.Error() != test.expectErr {
+					t.Fatalf("want error %q, have %v", test.expectErr, err)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			logger.Debug("debug")
+			logger.Info("info")
+			logger.Warn("warn")
+			logger.Error("error")
+			lines := strings.SplitAfter(strings.TrimSuffix(out.String(), "\n"), "\n")
+			if len(lines) != len(test.expect) {
+				t.Fatalf("want %d records, have %q", len(test.expect), out)
+			}
+			for i, line := range lines {
+				if !strings.HasSuffix(strings.TrimSuffix(line, "\n"), strings.TrimSuffix(test.expect[i], "\n")) {
+					t.Errorf("want record %q, have %q", test.expect[i], line)
+				}
+			}
+		})
+	}
+}
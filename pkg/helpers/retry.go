@@ -0,0 +1,56 @@
+package helpers
+
+import (
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy describes how an Executor retries commands which exit
+// unsuccessfully. Commands which cannot be started at all are never retried.
+// The zero value runs every command once.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a command is run at most, including
+	// the first attempt.
+	MaxAttempts int
+	// InitialBackoff is the wait before the second attempt. It doubles for
+	// every further attempt.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between attempts. Zero means no cap.
+	MaxBackoff time.Duration
+	// Jitter is the fraction, between 0 and 1, of every wait that is chosen at
+	// random, so that concurrent callers do not retry in lockstep.
+	Jitter float64
+	// Retryable reports whether a failed attempt is retried, given its exit
+	// code (-1 if it was killed by a signal, e.g. after a timeout) and the tail
+	// of its stderr. If nil, every failed attempt is retried.
+	Retryable func(exitCode int, stderr string) bool
+}
+
+func (p RetryPolicy) shouldRetry(attempt int, err error, stderr string) bool {
+	if attempt >= p.MaxAttempts {
+		return false
+	}
+	var exitErr exitCoder
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	return p.Retryable == nil || p.Retryable(exitErr.ExitCode(), stderr)
+}
+
+// backoff returns the wait after the given failed attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < attempt && (p.MaxBackoff == 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+
+	jitter := time.Duration(float64(d) * min(max(p.Jitter, 0), 1))
+	if jitter > 0 {
+		d = d - jitter + rand.N(jitter)
+	}
+	return d
+}
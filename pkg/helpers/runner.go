@@ -0,0 +1,98 @@
+package helpers
+
+import (
+	"context"
+	"os/exec"
+	"time"
+)
+
+// Runner runs the commands prepared by an Executor: it starts cmd, waits for
+// it to exit and returns the error of exec.Cmd.Wait, or any error with an
+// ExitCode() int method for a command which exited unsuccessfully.
+//
+// Executors use the Runner set with WithRunner, else the one on the context
+// (see ContextWithRunner), else the operating system. Tests can thus record
+// or script the commands run deep inside the code under test, e.g. with a
+// FakeRunner.
+type Runner interface {
+	RunCommand(ctx context.Context, cmd *exec.Cmd) error
+}
+
+type runnerKey struct{}
+
+// ContextWithRunner returns a copy of ctx which makes the Executors it is
+// passed to run commands with r.
+func ContextWithRunner(ctx context.Context, r Runner) context.Context {
+	return context.WithValue(ctx, runnerKey{}, r)
+}
+
+type exitCoder interface {
+	ExitCode() int
+}
+
+func (e *Executor) runner(ctx context.Context) Runner {
+	if e.cmdRunner != nil {
+		return e.cmdRunner
+	}
+	if r, ok := ctx.Value(runnerKey{}).(Runner); ok && r != nil {
+		return r
+	}
+	return processRunner{gracePeriod: e.gracePeriod}
+}
+
+// processRunner runs commands as processes of the operating system.
+type processRunner struct {
+	gracePeriod time.Duration
+}
+
+// RunCommand starts cmd and waits for it to exit. If ctx is cancelled first,
+// the process group of cmd is interrupted, and killed after the grace period;
+// only cmd itself is if it reads from a terminal (see setProcessGroup).
+func (r processRunner) RunCommand(ctx context.Context, cmd *exec.Cmd) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	group := setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	exited := make(chan struct{})
+	signalled := make(chan struct{})
+	go func() {
+		defer close(signalled)
+		select {
+		case <-exited:
+			return
+		case <-ctx.Done():
+		}
+
+		_ = interruptProcessGroup(cmd.Process, group)
+
+		// Kill the group even if the command itself exited in time: anything
+		// still running in it has been orphaned.
+		timer := time.NewTimer(r.gracePeriod)
+		defer timer.Stop()
+		select {
+		case <-exited:
+		case <-timer.C:
+		}
+		_ = killProcessGroup(cmd.Process, group)
+	}()
+
+	if group {
+		// Reaping the leader frees the ID of its process group for reuse, so
+		// the group is signalled only before: until then, the zombie of the
+		// leader holds the ID.
+		_ = waitExited(cmd.Process)
+		close(exited)
+		<-signalled
+		return cmd.Wait()
+	}
+
+	// Signalling cmd itself is safe once it is reaped.
+	err := cmd.Wait()
+	close(exited)
+	return err
+}
@@ -0,0 +1,33 @@
+package helpers
+
+// tailBuffer is an io.Writer which keeps only the last size bytes written to
+// it.
+type tailBuffer struct {
+	size int
+	buf  []byte
+}
+
+func newTailBuffer(size int) *tailBuffer {
+	return &tailBuffer{size: size}
+}
+
+// Write implements io.Writer.
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if b.size <= 0 {
+		return n, nil
+	}
+	if len(p) > b.size {
+		p = p[len(p)-b.size:]
+	}
+	if over := len(b.buf) + len(p) - b.size; over > 0 {
+		b.buf = append(b.buf[:0], b.buf[over:]...)
+	}
+	b.buf = append(b.buf, p...)
+	return n, nil
+}
+
+// String returns the kept bytes.
+func (b *tailBuffer) String() string {
+	return string(b.buf)
+}